@@ -0,0 +1,138 @@
+package accessor
+
+import (
+	"database/sql"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+)
+
+// Жизненный цикл одноразовых пользователей: превращение в обычного и удаление.
+// Сессии, расстановки и статистика удаляются вместе с пользователем через "on delete cascade".
+
+var stmtUpgradeDisposableUser *sql.Stmt
+
+func (db *DB) init19() (err error) {
+	//language=PostgreSQL
+	stmtUpgradeDisposableUser, err = db.Prepare(`
+with "upgraded" as (
+	update
+		"user"
+	set
+		"disposable" = false
+	where
+		"user"."id" = $1 and
+		"user"."disposable"
+	returning
+		"user"."id"
+), "login_information" as (
+	insert into "regular_login_information" (
+		"user_id",
+		"password_hash"
+	) select
+		"upgraded"."id", $2
+	from
+		"upgraded"
+), "statistics" as (
+	insert into "game_statistics" (
+		"user_id",
+		"games_played",
+		"wins"
+	) select
+		"upgraded"."id", 0, 0
+	from
+		"upgraded"
+	on conflict ("user_id") do nothing
+)
+select
+	count(*)
+from
+	"upgraded"
+;   `)
+	err = errors.Wrap(err, "init19: ")
+	return
+}
+
+// Делает одноразового пользователя обычным: логин, сессии и статистика остаются.
+// Один запрос, поэтому либо выполняется целиком, либо не выполняется.
+// upgraded == false, если пользователь уже обычный.
+func (db *DB) UpgradeDisposableUser(userID UserID, passwordHash string) (upgraded bool, err error) {
+	defer metrics.ObserveQuery("UpgradeDisposableUser", time.Now())
+	var count int
+	err = stmtUpgradeDisposableUser.QueryRow(userID, passwordHash).Scan(&count)
+	if err != nil {
+		err = errors.New("Error on exec 'UpgradeDisposableUser' statement: " + err.Error())
+		return
+	}
+	upgraded = count != 0
+	return
+}
+
+var stmtDeleteInactiveDisposableUsers *sql.Stmt
+
+func (db *DB) init20() (err error) {
+	//language=PostgreSQL
+	stmtDeleteInactiveDisposableUsers, err = db.Prepare(`
+delete from
+	"user"
+where
+	"user"."disposable" and
+	"user"."last_login_time" < now() - $1 * interval '1 second' and
+	not exists (
+		select
+			1
+		from
+			"session"
+		where
+			"session"."user_id" = "user"."id" and
+			"session"."last_seen_at" >= now() - $1 * interval '1 second'
+	)
+;   `)
+	err = errors.Wrap(err, "init20: ")
+	return
+}
+
+// удаляет одноразовых пользователей, от которых не было запросов дольше ttl, освобождая их логины.
+func (db *DB) DeleteInactiveDisposableUsers(ttl time.Duration) (deleted int64, err error) {
+	defer metrics.ObserveQuery("DeleteInactiveDisposableUsers", time.Now())
+	result, err := stmtDeleteInactiveDisposableUsers.Exec(int64(ttl / time.Second))
+	if err != nil {
+		err = errors.New("Error on exec 'DeleteInactiveDisposableUsers' statement: " + err.Error())
+		return
+	}
+	deleted, _ = result.RowsAffected()
+	return
+}
+
+var stmtDeleteDisposableUserBySessionTokenHash *sql.Stmt
+
+func (db *DB) init21() (err error) {
+	//language=PostgreSQL
+	stmtDeleteDisposableUserBySessionTokenHash, err = db.Prepare(`
+delete from
+	"user"
+using
+	"session"
+where
+	"session"."token_hash" = $1 and
+	"session"."user_id" = "user"."id" and
+	"user"."disposable"
+;   `)
+	err = errors.Wrap(err, "init21: ")
+	return
+}
+
+// при выходе одноразовый пользователь больше не нужен: войти в него снова нельзя.
+// Для обычного пользователя ничего не делает.
+func (db *DB) DeleteDisposableUserBySessionTokenHash(tokenHash string) (deleted bool, err error) {
+	defer metrics.ObserveQuery("DeleteDisposableUserBySessionTokenHash", time.Now())
+	result, err := stmtDeleteDisposableUserBySessionTokenHash.Exec(tokenHash)
+	if err != nil {
+		err = errors.New("Error on exec 'DeleteDisposableUserBySessionTokenHash' statement: " + err.Error())
+		return
+	}
+	rowsAffected, _ := result.RowsAffected()
+	deleted = rowsAffected != 0
+	return
+}
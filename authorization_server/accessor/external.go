@@ -0,0 +1,140 @@
+package accessor
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+)
+
+// Связь пользователей с аккаунтами внешних провайдеров, см. пакет oauth.
+
+var stmtSelectUserIDByExternalLogin *sql.Stmt
+
+func (db *DB) init26() (err error) {
+	//language=PostgreSQL
+	stmtSelectUserIDByExternalLogin, err = db.Prepare(`
+select
+	"external_login"."user_id"
+from
+	"external_login"
+where
+	"external_login"."provider" = $1 and
+	"external_login"."subject" = $2
+;   `)
+	err = errors.Wrap(err, "init26: ")
+	return
+}
+
+// exist == false, если аккаунт провайдера ещё ни с кем не связан.
+func (db *DB) SelectUserIDByExternalLogin(provider string, subject string) (exist bool, userID UserID, err error) {
+	defer metrics.ObserveQuery("SelectUserIDByExternalLogin", time.Now())
+	err = stmtSelectUserIDByExternalLogin.QueryRow(provider, subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = errors.New("Error on exec 'SelectUserIDByExternalLogin' statement: " + err.Error())
+		return
+	}
+	exist = true
+	return
+}
+
+var stmtInsertIntoExternalLogin *sql.Stmt
+
+func (db *DB) init27() (err error) {
+	//language=PostgreSQL
+	stmtInsertIntoExternalLogin, err = db.Prepare(`
+insert into "external_login" (
+	"user_id",
+	"provider",
+	"subject",
+	"created_at"
+) values (
+	$1, $2, $3, now()
+);
+	`)
+	err = errors.Wrap(err, "init27: ")
+	return
+}
+
+// связывает аккаунт провайдера с существующим пользователем.
+// isDuplicate == true, если этот аккаунт уже связан с кем-то или у пользователя уже есть
+// аккаунт этого провайдера.
+func (db *DB) InsertIntoExternalLogin(userID UserID, provider string, subject string) (isDuplicate bool, err error) {
+	defer metrics.ObserveQuery("InsertIntoExternalLogin", time.Now())
+	_, err = stmtInsertIntoExternalLogin.Exec(userID, provider, subject)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			isDuplicate = true
+			err = nil
+			return
+		}
+		err = errors.New("Error on exec 'InsertIntoExternalLogin' statement: " + err.Error())
+	}
+	return
+}
+
+var stmtInsertExternalUser *sql.Stmt
+
+func (db *DB) init28() (err error) {
+	//language=PostgreSQL
+	stmtInsertExternalUser, err = db.Prepare(`
+with "new_user" as (
+	insert into "user" (
+		"login",
+		"avatar_address",
+		"disposable",
+		"last_login_time"
+	) values (
+		$1, $2, false, now()
+	) returning
+		"user"."id"
+), "statistics" as (
+	insert into "game_statistics" (
+		"user_id",
+		"games_played",
+		"wins"
+	) select
+		"new_user"."id", 0, 0
+	from
+		"new_user"
+)
+insert into "external_login" (
+	"user_id",
+	"provider",
+	"subject",
+	"created_at"
+) select
+	"new_user"."id", $3, $4, now()
+from
+	"new_user"
+returning
+	"external_login"."user_id"
+;   `)
+	err = errors.Wrap(err, "init28: ")
+	return
+}
+
+// Создаёт обычного пользователя без пароля, связанного с аккаунтом провайдера.
+// Один запрос: пользователь без связи не останется, если тот же аккаунт одновременно
+// входит с другого устройства. Тогда linked == true, а пользователя надо искать заново.
+// isDuplicateLogin == true, если логин занят.
+func (db *DB) InsertExternalUser(login string, avatarAddress string, provider string, subject string) (id UserID, isDuplicateLogin bool, linked bool, err error) {
+	defer metrics.ObserveQuery("InsertExternalUser", time.Now())
+	err = stmtInsertExternalUser.QueryRow(login, avatarAddress, provider, subject).Scan(&id)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		isDuplicateLogin = pqErr.Constraint != "external_login_identity"
+		linked = !isDuplicateLogin
+		err = nil
+		return
+	}
+	if err != nil {
+		err = errors.New("Error on exec 'InsertExternalUser' statement: " + err.Error())
+	}
+	return
+}
@@ -9,7 +9,9 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/pkg/errors"
 	"strconv"
+	"time"
 
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
 	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
 )
 
@@ -37,10 +39,9 @@ func ConnectToDatabase(DataSourceName string) (db DB, err error) {
 }
 
 // подготовит все prepared statement,
-// должна быть вызвана после соединения с базой.
+// должна быть вызвана после соединения с базой и миграций.
 func (db *DB) InitDatabase() (err error) {
 	initAll := []func() error{
-		db.init00,
 		db.init01,
 		db.init02,
 		db.init03,
@@ -51,11 +52,29 @@ func (db *DB) InitDatabase() (err error) {
 		db.init08,
 		db.init09,
 		db.init10,
+		db.init11,
+		db.init12,
+		db.init13,
+		db.init14,
+		db.init15,
+		db.init16,
+		db.init17,
+		db.init18,
+		db.init19,
+		db.init20,
+		db.init21,
+		db.init22,
+		db.init23,
+		db.init24,
+		db.init25,
+		db.init26,
+		db.init27,
+		db.init28,
 	}
 	for i, init := range initAll {
 		err = init()
 		if err != nil {
-			err = errors.Wrap(err, "during preparing function 'accessor.init"+strconv.Itoa(i)+"': ")
+			err = errors.Wrap(err, "during preparing function 'accessor.init"+strconv.Itoa(i+1)+"': ")
 			break
 		}
 	}
@@ -83,6 +102,7 @@ insert into "user"(
 
 // Такие функции скрывают нетипизированность prepared statement.
 func (db *DB) InsertIntoUser(login string, avatarAddress string, disposable bool) (id UserID, isDuplicate bool, err error) {
+	defer metrics.ObserveQuery("InsertIntoUser", time.Now())
 	err = stmtInsertIntoUser.QueryRow(login, avatarAddress, disposable).Scan(&id)
 	if err != nil {
 		isDuplicate = err.(*pq.Error).Code == "23505"
@@ -112,6 +132,7 @@ insert into "regular_login_information"(
 }
 
 func (db *DB) InsertIntoRegularLoginInformation(userID UserID, passwordHash string) (err error) {
+	defer metrics.ObserveQuery("InsertIntoRegularLoginInformation", time.Now())
 	_, err = stmtInsertIntoRegularLoginInformation.Exec(userID, passwordHash)
 	if err != nil {
 		err = errors.New("Error on exec 'InsertIntoRegularLoginInformation' statement: " + err.Error())
@@ -137,6 +158,7 @@ insert into "game_statistics" (
 }
 
 func (db *DB) InsertIntoGameStatistics(userId UserID, gamesPlayed int32, wins int32) (err error) {
+	defer metrics.ObserveQuery("InsertIntoGameStatistics", time.Now())
 	_, err = stmtInsertIntoGameStatistics.Exec(userId, gamesPlayed, wins)
 	if err != nil {
 		err = errors.New("Error on exec 'insertIntoGameStatistics' statement: " + err.Error())
@@ -144,32 +166,6 @@ func (db *DB) InsertIntoGameStatistics(userId UserID, gamesPlayed int32, wins in
 	return err
 }
 
-var stmtInsertIntoCurrentLogin *sql.Stmt
-
-func (db *DB) init04() (err error) {
-	//language=PostgreSQL
-	stmtInsertIntoCurrentLogin, err = db.Prepare(`
-insert into "current_login" (
-	"user_id",
-    "authorization_token" -- cookie пользователя
-) values (
-	$1, $2
-) on conflict ("user_id") do update set 
-    "authorization_token" = excluded."authorization_token"
-;   `)
-	err = errors.Wrap(err, "init04: ")
-	return
-}
-
-// update or insert
-func (db *DB) UpsertIntoCurrentLogin(userID UserID, authorizationToken string) (err error) {
-	_, err = stmtInsertIntoCurrentLogin.Exec(userID, authorizationToken)
-	if err != nil {
-		err = errors.New("Error on exec 'InsertIntoGameStatistics' statement: " + err.Error())
-	}
-	return err
-}
-
 var stmtSelectLeaderBoard *sql.Stmt
 
 func (db *DB) init05() (err error) {
@@ -198,6 +194,7 @@ offset
 }
 
 func (db *DB) SelectLeaderBoard(limit int, offset int) (usersInformation types.PublicUsersInformation, err error) {
+	defer metrics.ObserveQuery("SelectLeaderBoard", time.Now())
 	defer func() {
 		if err != nil {
 			err = errors.New("Error on exec 'SelectLeaderBoard' statement: " + err.Error())
@@ -248,6 +245,7 @@ where
 }
 
 func (db *DB) SelectUserByLogin(login string) (userInformation types.PublicUserInformation, err error) {
+	defer metrics.ObserveQuery("SelectUserByLogin", time.Now())
 	if err = stmtSelectUserByLogin.QueryRow(login).Scan(
 		&userInformation.Login,
 		&userInformation.AvatarAddress,
@@ -259,35 +257,38 @@ func (db *DB) SelectUserByLogin(login string) (userInformation types.PublicUserI
 	return
 }
 
-var stmtSelectUserIDByLoginPassword *sql.Stmt
+var stmtSelectRegularLoginInformationByLogin *sql.Stmt
 
 func (db *DB) init07() (err error) {
 	//language=PostgreSQL
-	stmtSelectUserIDByLoginPassword, err = db.Prepare(`
+	stmtSelectRegularLoginInformationByLogin, err = db.Prepare(`
 select
-	"user"."id"
+	"user"."id",
+	"regular_login_information"."password_hash"
 from 
 	"user",
 	"regular_login_information"
 where 
 	"user"."login" = $1 and
-	"user"."id" = "regular_login_information"."user_id" and 
-	"regular_login_information"."password_hash" = $2
+	"user"."id" = "regular_login_information"."user_id"
 ;   `)
 	err = errors.Wrap(err, "init07: ")
 	return
 }
 
-func (db *DB) SelectUserIdByLoginPasswordHash(login string, passwordHash string) (exist bool, userId UserID, err error) {
-	err = stmtSelectUserIDByLoginPassword.QueryRow(login, passwordHash).Scan(
-		&userId,
+// хеш пароля сравнивается в Go: у каждого пользователя своя соль, см. пакет password.
+func (db *DB) SelectRegularLoginInformationByLogin(login string) (exist bool, info RegularLoginInformation, err error) {
+	defer metrics.ObserveQuery("SelectRegularLoginInformationByLogin", time.Now())
+	err = stmtSelectRegularLoginInformationByLogin.QueryRow(login).Scan(
+		&info.UserID,
+		&info.PasswordHash,
 	)
 	if err != nil {
 		if err.Error() == "sql: no rows in result set" {
 			err = nil
 			// exist == false as default.
 		} else {
-			err = errors.New("Error on exec 'SelectUserIdByLoginPasswordHash' statement: " + err.Error())
+			err = errors.New("Error on exec 'SelectRegularLoginInformationByLogin' statement: " + err.Error())
 		}
 	} else {
 		exist = true
@@ -295,28 +296,6 @@ func (db *DB) SelectUserIdByLoginPasswordHash(login string, passwordHash string)
 	return
 }
 
-var stmtDropUsersSession *sql.Stmt
-
-func (db *DB) init08() (err error) {
-	//language=PostgreSQL
-	stmtDropUsersSession, err = db.Prepare(`
-delete from
-    "current_login"
-where 
-    "current_login"."authorization_token" = $1
-;   `)
-	err = errors.Wrap(err, "init08: ")
-	return
-}
-
-func (db *DB) DropUsersSession(authorizationToken string) (err error) {
-	_, err = stmtDropUsersSession.Exec(authorizationToken)
-	if err != nil {
-		err = errors.New("Error on exec 'dropUsersSession' statement: " + err.Error())
-	}
-	return err
-}
-
 var stmtUpdateUsersAvatarByLogin *sql.Stmt
 
 func (db *DB) init09() (err error) {
@@ -326,8 +305,6 @@ update
     "user"
 set
     "avatar_address" = $2
-from
-    "current_login"
 where
     "user"."login" = $1
 ;    `)
@@ -336,6 +313,7 @@ where
 }
 
 func (db *DB) UpdateUsersAvatarByLogin(login string, avatarAddress string) (err error) {
+	defer metrics.ObserveQuery("UpdateUsersAvatarByLogin", time.Now())
 	result, err := stmtUpdateUsersAvatarByLogin.Exec(login, avatarAddress)
 	if err != nil {
 		err = errors.New("Error on exec 'UpdateUsersAvatarByLogin' statement: " + err.Error())
@@ -347,44 +325,160 @@ func (db *DB) UpdateUsersAvatarByLogin(login string, avatarAddress string) (err
 	return
 }
 
-var stmtSelectUserLoginBySessionID *sql.Stmt
+var stmtSelectAvatarAddressIsUsed *sql.Stmt
 
-func (db *DB) init10() (err error) {
+func (db *DB) init22() (err error) {
 	//language=PostgreSQL
-	stmtSelectUserLoginBySessionID, err = db.Prepare(`
-select 	
-	"user"."id",
-	"user"."login",
-	"user"."avatar_address",
-	"user"."disposable",
-	"user"."last_login_time"
-from 
-	"user", "current_login"
+	stmtSelectAvatarAddressIsUsed, err = db.Prepare(`
+select exists(
+	select
+		1
+	from
+		"user"
+	where
+		"user"."avatar_address" = $1
+)
+;   `)
+	err = errors.Wrap(err, "init22: ")
+	return
+}
+
+// одинаковые картинки получают одинаковый адрес, поэтому файл старой аватарки
+// удаляется, только если больше ни у кого её нет.
+func (db *DB) SelectAvatarAddressIsUsed(avatarAddress string) (used bool, err error) {
+	defer metrics.ObserveQuery("SelectAvatarAddressIsUsed", time.Now())
+	err = stmtSelectAvatarAddressIsUsed.QueryRow(avatarAddress).Scan(&used)
+	if err != nil {
+		err = errors.New("Error on exec 'SelectAvatarAddressIsUsed' statement: " + err.Error())
+	}
+	return
+}
+
+var stmtUpsertIntoFormation *sql.Stmt
+
+func (db *DB) init11() (err error) {
+	//language=PostgreSQL
+	stmtUpsertIntoFormation, err = db.Prepare(`
+insert into "formation" (
+	"user_id",
+	"name",
+	"weapons"
+) values (
+	$1, $2, $3
+) on conflict ("user_id", "name") do update set
+	"weapons" = excluded."weapons"
+;   `)
+	err = errors.Wrap(err, "init11: ")
+	return
+}
+
+// update or insert, расстановка с тем же именем перезаписывается.
+func (db *DB) UpsertIntoFormation(userID UserID, formation types.Formation) (err error) {
+	defer metrics.ObserveQuery("UpsertIntoFormation", time.Now())
+	_, err = stmtUpsertIntoFormation.Exec(userID, formation.Name, pq.Array(formation.Weapons[:]))
+	if err != nil {
+		err = errors.New("Error on exec 'UpsertIntoFormation' statement: " + err.Error())
+	}
+	return
+}
+
+var stmtSelectFormationsByUserID *sql.Stmt
+
+func (db *DB) init12() (err error) {
+	//language=PostgreSQL
+	stmtSelectFormationsByUserID, err = db.Prepare(`
+select
+	"formation"."name",
+	"formation"."weapons"
+from
+	"formation"
 where
-	"current_login"."authorization_token" = $1 and
-	"current_login"."user_id" = "user"."id"
-;    `)
-	err = errors.Wrap(err, "init10: ")
+	"formation"."user_id" = $1
+order by
+	"formation"."name"
+;   `)
+	err = errors.Wrap(err, "init12: ")
 	return
 }
 
-func (db *DB) SelectUserBySessionId(authorizationToken string) (exist bool, user User, err error) {
-	err = stmtSelectUserLoginBySessionID.QueryRow(authorizationToken).Scan(
-		&user.Id,
-		&user.Login,
-		&user.AvatarAddress,
-		&user.Disposable,
-		&user.LastLoginTime,
-	)
+func (db *DB) SelectFormationsByUserID(userID UserID) (formations types.Formations, err error) {
+	defer metrics.ObserveQuery("SelectFormationsByUserID", time.Now())
+	defer func() {
+		if err != nil {
+			err = errors.New("Error on exec 'SelectFormationsByUserID' statement: " + err.Error())
+		}
+	}()
+	rows, err := stmtSelectFormationsByUserID.Query(userID)
 	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			err = nil
-			// exist == false as default.
-		} else {
-			err = errors.New("Error on exec 'SelectUserBySid' statment: " + err.Error())
+		return
+	}
+	defer func() { _ = rows.Close() }()
+	formations = types.Formations{}
+	for rows.Next() {
+		formation := types.Formation{}
+		var weapons []string
+		if err = rows.Scan(
+			&formation.Name,
+			pq.Array(&weapons),
+		); err != nil {
+			return
 		}
-	} else {
-		exist = true
+		copy(formation.Weapons[:], weapons)
+		formations = append(formations, formation)
+	}
+	// ошибка, прервавшая чтение строк, видна только после цикла.
+	err = rows.Err()
+	return
+}
+
+var stmtDeleteFromFormation *sql.Stmt
+
+func (db *DB) init13() (err error) {
+	//language=PostgreSQL
+	stmtDeleteFromFormation, err = db.Prepare(`
+delete from
+	"formation"
+where
+	"formation"."user_id" = $1 and
+	"formation"."name" = $2
+;   `)
+	err = errors.Wrap(err, "init13: ")
+	return
+}
+
+func (db *DB) DeleteFromFormation(userID UserID, name string) (exist bool, err error) {
+	defer metrics.ObserveQuery("DeleteFromFormation", time.Now())
+	result, err := stmtDeleteFromFormation.Exec(userID, name)
+	if err != nil {
+		err = errors.New("Error on exec 'DeleteFromFormation' statement: " + err.Error())
+		return
+	}
+	rowsAffected, _ := result.RowsAffected()
+	exist = rowsAffected != 0
+	return
+}
+
+var stmtUpdateRegularLoginInformationPasswordHash *sql.Stmt
+
+func (db *DB) init14() (err error) {
+	//language=PostgreSQL
+	stmtUpdateRegularLoginInformationPasswordHash, err = db.Prepare(`
+update
+	"regular_login_information"
+set
+	"password_hash" = $2
+where
+	"regular_login_information"."user_id" = $1
+;   `)
+	err = errors.Wrap(err, "init14: ")
+	return
+}
+
+func (db *DB) UpdateRegularLoginInformationPasswordHash(userID UserID, passwordHash string) (err error) {
+	defer metrics.ObserveQuery("UpdateRegularLoginInformationPasswordHash", time.Now())
+	_, err = stmtUpdateRegularLoginInformationPasswordHash.Exec(userID, passwordHash)
+	if err != nil {
+		err = errors.New("Error on exec 'UpdateRegularLoginInformationPasswordHash' statement: " + err.Error())
 	}
 	return
 }
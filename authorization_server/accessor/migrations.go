@@ -0,0 +1,308 @@
+// Схема базы данных, c которой работает сервис.
+// Скомпилированный бинарник содержит схему, это серьёзно упрощает первое развёртывание.
+//
+// Схема меняется только новыми миграциями в конце списка, применённые миграции не правятся:
+// они уже выполнены на работающих базах. Номер последней применённой миграции хранится
+// в таблице "schema_version".
+
+package accessor
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+)
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	// возвращает схему к предыдущей версии, данные новых таблиц теряются.
+	down string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial",
+		// "if not exists": базы, созданные до появления миграций, принимаются как есть.
+		//language=PostgreSQL
+		up: `
+create table if not exists "user" (
+  "id"              serial4 primary key,
+  -- видимое другим игрокам имя пользователя
+  "login"           text      not null unique,
+  -- адрес относительно корня сайта: '/media/name-src32.ext'
+  "avatar_address"  text      not null,
+  -- Если True - пользователь не залогинен, играет просто так.
+  -- Такие пользователи создаются, когда входят в игру с одним только именем,
+  -- и удаляются при выходе или после --disposable-user-ttl без запросов.
+  "disposable"      boolean   not null,
+  "last_login_time" timestamp not null
+);
+
+-- не более одной строчки на пользователя в нижних трёх таблицах
+create table if not exists "regular_login_information" (
+  "id"            serial4 primary key,
+  "user_id"       integer not null unique references "user" ("id") on delete cascade,
+  "password_hash" text not null
+);
+
+-- данные для таблицы лидеров
+create table if not exists "game_statistics" (
+  "id"           serial4 primary key,
+  "user_id"      integer not null unique references "user" ("id") on delete cascade,
+  "games_played" integer not null, -- количество начатых игр
+  "wins"         integer not null -- количество доведённых до победного конца
+);
+
+-- текущая принадлежность к игре.
+-- допущение - только одна игра в один момент времени.
+create table if not exists "current_login" (
+  "id"                  serial4 primary key,
+  "user_id"             integer not null unique references "user" ("id") on delete cascade,
+  -- токен авторицации, ставящийся как cookie пользователю
+  "authorization_token" text null unique
+);`,
+		//language=PostgreSQL
+		down: `
+drop table "current_login";
+drop table "game_statistics";
+drop table "regular_login_information";
+drop table "user";`,
+	},
+	{
+		version: 2,
+		name:    "formation",
+		//language=PostgreSQL
+		up: `
+-- именованные расстановки персонажей для быстрого старта игры.
+create table if not exists "formation" (
+  "id"      serial4 primary key,
+  "user_id" integer not null references "user" ("id") on delete cascade,
+  "name"    text    not null,
+  -- 14 оружий в порядке метода "upload_map" игрового сервера
+  "weapons" text[]  not null,
+  unique ("user_id", "name")
+);`,
+		//language=PostgreSQL
+		down: `
+drop table "formation";`,
+	},
+	{
+		version: 3,
+		name:    "session",
+		//language=PostgreSQL
+		up: `
+-- сессии пользователя, по одной на каждое устройство.
+create table if not exists "session" (
+  "id"           serial4   primary key,
+  "user_id"      integer   not null references "user" ("id") on delete cascade,
+  -- sha256 токена в hex, сам токен есть только в cookie SessionId пользователя
+  "token_hash"   text      not null unique,
+  -- заголовок User-Agent при входе, что бы пользователь узнал устройство в списке сессий
+  "user_agent"   text      not null,
+  "created_at"   timestamp not null,
+  "last_seen_at" timestamp not null,
+  -- сдвигается на --session-lifetime вперёд, пока сессией пользуются
+  "expires_at"   timestamp not null
+);
+create index if not exists "session_user_id" on "session" ("user_id");
+
+-- токены в "current_login" хранились открыто и были предсказуемы, пользователи входят заново.
+drop table if exists "current_login";`,
+		//language=PostgreSQL
+		down: `
+create table "current_login" (
+  "id"                  serial4 primary key,
+  "user_id"             integer not null unique references "user" ("id") on delete cascade,
+  "authorization_token" text null unique
+);
+drop table "session";`,
+	},
+	{
+		version: 4,
+		name:    "session_csrf_token",
+		//language=PostgreSQL
+		up: `
+-- у старых сессий нет токена CSRF, пользователи входят заново.
+delete from "session";
+-- ставится в cookie "CSRFToken", state-changing запросы должны повторить его в
+-- заголовке "X-CSRF-Token" или поле формы "csrf_token".
+alter table "session" add column "csrf_token" text not null;`,
+		//language=PostgreSQL
+		down: `
+alter table "session" drop column "csrf_token";`,
+	},
+	{
+		version: 5,
+		name:    "login_history",
+		//language=PostgreSQL
+		up: `
+-- прежние логины пользователя: по ним можно найти, кто играл под старым именем.
+create table if not exists "login_history" (
+  "id"         serial4   primary key,
+  "user_id"    integer   not null references "user" ("id") on delete cascade,
+  "old_login"  text      not null,
+  "new_login"  text      not null,
+  "changed_at" timestamp not null
+);
+create index if not exists "login_history_user_id" on "login_history" ("user_id");`,
+		//language=PostgreSQL
+		down: `
+drop table "login_history";`,
+	},
+	{
+		version: 6,
+		name:    "external_login",
+		//language=PostgreSQL
+		up: `
+-- аккаунты ВКонтакте и провайдеров OpenID Connect, через которые входит пользователь.
+-- У пользователя, созданного входом через провайдера, нет строки в "regular_login_information".
+create table if not exists "external_login" (
+  "id"         serial4   primary key,
+  "user_id"    integer   not null references "user" ("id") on delete cascade,
+  -- имя провайдера из конфигурации сервера: 'vk', 'oidc'
+  "provider"   text      not null,
+  -- постоянный id пользователя у провайдера
+  "subject"    text      not null,
+  "created_at" timestamp not null,
+  constraint "external_login_identity" unique ("provider", "subject"),
+  -- не больше одного аккаунта каждого провайдера
+  constraint "external_login_user_provider" unique ("user_id", "provider")
+);`,
+		//language=PostgreSQL
+		down: `
+drop table "external_login";`,
+	},
+}
+
+// версия схемы, с которой работает этот бинарник.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// База была обновлена более новой версией сервиса, запросы этой версии могут ей не подойти.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary knows")
+
+// произвольное число, общее для всех экземпляров сервиса: миграции выполняются по очереди.
+const migrationLockKey = 4242
+
+func (db *DB) createSchemaVersionTable() (err error) {
+	//language=PostgreSQL
+	_, err = db.Exec(`
+create table if not exists "schema_version" (
+  "version"    integer   primary key,
+  "name"       text      not null,
+  "applied_at" timestamp not null
+);  `)
+	err = errors.Wrap(err, "create table 'schema_version': ")
+	return
+}
+
+// номер последней применённой миграции, 0 у пустой базы.
+func (db *DB) SchemaVersion() (version int, err error) {
+	err = db.createSchemaVersionTable()
+	if err != nil {
+		return
+	}
+	//language=PostgreSQL
+	err = db.QueryRow(`select coalesce(max("version"), 0) from "schema_version";`).Scan(&version)
+	err = errors.Wrap(err, "select schema version: ")
+	return
+}
+
+// Отказывает, если версия схемы не совпадает с LatestSchemaVersion:
+// старую схему нужно обновить, с новой этот бинарник работать не должен.
+func (db *DB) CheckSchemaVersion() (err error) {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return
+	}
+	switch {
+	case version > LatestSchemaVersion():
+		err = errors.Wrap(ErrSchemaTooNew, "database version "+strconv.Itoa(version)+
+			", binary version "+strconv.Itoa(LatestSchemaVersion()))
+	case version < LatestSchemaVersion():
+		err = errors.New("database schema version " + strconv.Itoa(version) + " is outdated, binary version " +
+			strconv.Itoa(LatestSchemaVersion()) + ": run 'authorization_server migrate'")
+	}
+	return
+}
+
+// Применяет миграции вверх или откатывает вниз до версии target.
+// Каждая миграция выполняется в своей транзакции вместе с записью в "schema_version",
+// поэтому прерванный запуск оставляет базу в одной из версий.
+func (db *DB) MigrateTo(target int) (err error) {
+	if target < 0 || target > LatestSchemaVersion() {
+		err = errors.New("unknown schema version " + strconv.Itoa(target))
+		return
+	}
+	err = db.createSchemaVersionTable()
+	if err != nil {
+		return
+	}
+	for {
+		var done bool
+		done, err = db.migrateStep(target)
+		if err != nil || done {
+			return
+		}
+	}
+}
+
+// одна миграция в сторону target. done == true, если база уже в версии target.
+func (db *DB) migrateStep(target int) (done bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		err = errors.Wrap(err, "begin migration: ")
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = errors.Wrap(tx.Commit(), "commit migration: ")
+	}()
+
+	// другой экземпляр, запущенный одновременно, ждёт здесь и потом видит уже новую версию.
+	//language=PostgreSQL
+	_, err = tx.Exec(`select pg_advisory_xact_lock($1);`, migrationLockKey)
+	if err != nil {
+		err = errors.Wrap(err, "lock schema_version: ")
+		return
+	}
+	var version int
+	//language=PostgreSQL
+	err = tx.QueryRow(`select coalesce(max("version"), 0) from "schema_version";`).Scan(&version)
+	if err != nil {
+		err = errors.Wrap(err, "select schema version: ")
+		return
+	}
+
+	switch {
+	case version > LatestSchemaVersion():
+		err = errors.Wrap(ErrSchemaTooNew, "database version "+strconv.Itoa(version)+
+			", binary version "+strconv.Itoa(LatestSchemaVersion()))
+	case version == target:
+		done = true
+	case version < target:
+		m := migrations[version]
+		_, err = tx.Exec(m.up)
+		if err == nil {
+			//language=PostgreSQL
+			_, err = tx.Exec(`insert into "schema_version" ("version", "name", "applied_at") values ($1, $2, now());`,
+				m.version, m.name)
+		}
+		err = errors.Wrap(err, "migration "+strconv.Itoa(m.version)+" '"+m.name+"' up: ")
+	default:
+		m := migrations[version-1]
+		_, err = tx.Exec(m.down)
+		if err == nil {
+			//language=PostgreSQL
+			_, err = tx.Exec(`delete from "schema_version" where "version" = $1;`, m.version)
+		}
+		err = errors.Wrap(err, "migration "+strconv.Itoa(m.version)+" '"+m.name+"' down: ")
+	}
+	return
+}
@@ -0,0 +1,157 @@
+package accessor
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+)
+
+// Изменение профиля пользователем: пароль, логин и удаление аккаунта.
+// Статистика, сессии, расстановки и история логинов удаляются вместе с пользователем через "on delete cascade".
+
+var stmtSelectPasswordHashByUserID *sql.Stmt
+
+func (db *DB) init23() (err error) {
+	//language=PostgreSQL
+	stmtSelectPasswordHashByUserID, err = db.Prepare(`
+select
+	"regular_login_information"."password_hash"
+from
+	"regular_login_information"
+where
+	"regular_login_information"."user_id" = $1
+;   `)
+	err = errors.Wrap(err, "init23: ")
+	return
+}
+
+// exist == false у одноразового пользователя: пароля у него нет.
+func (db *DB) SelectPasswordHashByUserID(userID UserID) (exist bool, passwordHash string, err error) {
+	defer metrics.ObserveQuery("SelectPasswordHashByUserID", time.Now())
+	err = stmtSelectPasswordHashByUserID.QueryRow(userID).Scan(&passwordHash)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = errors.New("Error on exec 'SelectPasswordHashByUserID' statement: " + err.Error())
+		return
+	}
+	exist = true
+	return
+}
+
+var stmtUpdateProfile *sql.Stmt
+
+func (db *DB) init24() (err error) {
+	//language=PostgreSQL
+	stmtUpdateProfile, err = db.Prepare(`
+with "previous" as (
+	select
+		"user"."login"
+	from
+		"user"
+	where
+		"user"."id" = $1
+), "renamed" as (
+	update
+		"user"
+	set
+		"login" = $2
+	where
+		"user"."id" = $1 and
+		$2 <> '' and
+		"user"."login" <> $2
+	returning
+		"user"."id"
+), "history" as (
+	insert into "login_history" (
+		"user_id",
+		"old_login",
+		"new_login",
+		"changed_at"
+	) select
+		"renamed"."id", "previous"."login", $2, now()
+	from
+		"renamed", "previous"
+), "changed_password" as (
+	update
+		"regular_login_information"
+	set
+		"password_hash" = $3
+	where
+		"regular_login_information"."user_id" = $1 and
+		$3 <> ''
+	returning
+		"regular_login_information"."user_id"
+), "ended_sessions" as (
+	delete from
+		"session"
+	where
+		"session"."user_id" = $1 and
+		"session"."id" <> $4 and
+		exists (select 1 from "changed_password")
+)
+select
+	exists (select 1 from "previous")
+;   `)
+	err = errors.Wrap(err, "init24: ")
+	return
+}
+
+// Меняет логин и пароль одним запросом: либо применяется всё, либо ничего.
+// Пустой login или passwordHash - не менять. Прежний логин записывается в "login_history",
+// при смене пароля завершаются все сессии пользователя, кроме currentSessionID:
+// тот, кто знал старый пароль, теряет доступ.
+// isDuplicate == true, если логин занят: уникальность проверяет индекс, а не отдельный select,
+// поэтому два одновременных переименования в один логин не пройдут оба.
+// exist == false, если пользователя уже удалили.
+func (db *DB) UpdateProfile(userID UserID, login string, passwordHash string, currentSessionID SessionID) (exist bool, isDuplicate bool, err error) {
+	defer metrics.ObserveQuery("UpdateProfile", time.Now())
+	err = stmtUpdateProfile.QueryRow(userID, login, passwordHash, currentSessionID).Scan(&exist)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			isDuplicate = true
+			err = nil
+			return
+		}
+		err = errors.New("Error on exec 'UpdateProfile' statement: " + err.Error())
+	}
+	return
+}
+
+var stmtDeleteUser *sql.Stmt
+
+func (db *DB) init25() (err error) {
+	//language=PostgreSQL
+	stmtDeleteUser, err = db.Prepare(`
+delete from
+	"user"
+where
+	"user"."id" = $1
+returning
+	"user"."avatar_address"
+;   `)
+	err = errors.Wrap(err, "init25: ")
+	return
+}
+
+// Удаляет пользователя со всеми данными. avatarAddress нужен, что бы удалить файл аватарки:
+// в базе после удаления его уже нет.
+func (db *DB) DeleteUser(userID UserID) (exist bool, avatarAddress string, err error) {
+	defer metrics.ObserveQuery("DeleteUser", time.Now())
+	err = stmtDeleteUser.QueryRow(userID).Scan(&avatarAddress)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = errors.New("Error on exec 'DeleteUser' statement: " + err.Error())
+		return
+	}
+	exist = true
+	return
+}
@@ -0,0 +1,256 @@
+package accessor
+
+import (
+	"database/sql"
+	"github.com/pkg/errors"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+)
+
+// Сессии пользователя. В базе лежит только sha256 токена: утечка таблицы
+// не даёт войти под чужим именем.
+
+var stmtInsertIntoSession *sql.Stmt
+
+func (db *DB) init04() (err error) {
+	//language=PostgreSQL
+	stmtInsertIntoSession, err = db.Prepare(`
+insert into "session" (
+	"user_id",
+	"token_hash",
+	"user_agent",
+	"csrf_token",
+	"created_at",
+	"last_seen_at",
+	"expires_at"
+) values (
+	$1, $2, $3, $4, now(), now(), now() + $5 * interval '1 second'
+)
+;   `)
+	err = errors.Wrap(err, "init04: ")
+	return
+}
+
+// lifetime - через сколько сессия истечёт, если ей не пользоваться.
+func (db *DB) InsertIntoSession(userID UserID, tokenHash string, userAgent string, csrfToken string, lifetime time.Duration) (err error) {
+	defer metrics.ObserveQuery("InsertIntoSession", time.Now())
+	_, err = stmtInsertIntoSession.Exec(userID, tokenHash, userAgent, csrfToken, int64(lifetime/time.Second))
+	if err != nil {
+		err = errors.New("Error on exec 'InsertIntoSession' statement: " + err.Error())
+	}
+	return
+}
+
+var stmtDeleteSessionByTokenHash *sql.Stmt
+
+func (db *DB) init08() (err error) {
+	//language=PostgreSQL
+	stmtDeleteSessionByTokenHash, err = db.Prepare(`
+delete from
+	"session"
+where
+	"session"."token_hash" = $1
+;   `)
+	err = errors.Wrap(err, "init08: ")
+	return
+}
+
+func (db *DB) DeleteSessionByTokenHash(tokenHash string) (err error) {
+	defer metrics.ObserveQuery("DeleteSessionByTokenHash", time.Now())
+	_, err = stmtDeleteSessionByTokenHash.Exec(tokenHash)
+	if err != nil {
+		err = errors.New("Error on exec 'DeleteSessionByTokenHash' statement: " + err.Error())
+	}
+	return
+}
+
+var stmtSelectUserBySessionTokenHash *sql.Stmt
+
+func (db *DB) init10() (err error) {
+	//language=PostgreSQL
+	stmtSelectUserBySessionTokenHash, err = db.Prepare(`
+select
+	"user"."id",
+	"user"."login",
+	"user"."avatar_address",
+	"user"."disposable",
+	"user"."last_login_time",
+	"session"."id",
+	"session"."user_agent",
+	"session"."csrf_token",
+	"session"."created_at",
+	"session"."last_seen_at",
+	"session"."expires_at"
+from
+	"user", "session"
+where
+	"session"."token_hash" = $1 and
+	"session"."expires_at" > now() and
+	"session"."user_id" = "user"."id"
+;    `)
+	err = errors.Wrap(err, "init10: ")
+	return
+}
+
+// истёкшие сессии считаются несуществующими.
+func (db *DB) SelectUserBySessionTokenHash(tokenHash string) (exist bool, user User, session Session, err error) {
+	defer metrics.ObserveQuery("SelectUserBySessionTokenHash", time.Now())
+	err = stmtSelectUserBySessionTokenHash.QueryRow(tokenHash).Scan(
+		&user.Id,
+		&user.Login,
+		&user.AvatarAddress,
+		&user.Disposable,
+		&user.LastLoginTime,
+		&session.Id,
+		&session.UserAgent,
+		&session.CSRFToken,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = nil
+			// exist == false as default.
+		} else {
+			err = errors.New("Error on exec 'SelectUserBySessionTokenHash' statement: " + err.Error())
+		}
+		return
+	}
+	session.UserID = user.Id
+	exist = true
+	return
+}
+
+var stmtRenewSession *sql.Stmt
+
+func (db *DB) init15() (err error) {
+	//language=PostgreSQL
+	stmtRenewSession, err = db.Prepare(`
+update
+	"session"
+set
+	"last_seen_at" = now(),
+	"expires_at" = now() + $2 * interval '1 second'
+where
+	"session"."id" = $1
+;   `)
+	err = errors.Wrap(err, "init15: ")
+	return
+}
+
+// сдвигает срок жизни активной сессии.
+func (db *DB) RenewSession(sessionID SessionID, lifetime time.Duration) (err error) {
+	defer metrics.ObserveQuery("RenewSession", time.Now())
+	_, err = stmtRenewSession.Exec(sessionID, int64(lifetime/time.Second))
+	if err != nil {
+		err = errors.New("Error on exec 'RenewSession' statement: " + err.Error())
+	}
+	return
+}
+
+var stmtSelectSessionsByUserID *sql.Stmt
+
+func (db *DB) init16() (err error) {
+	//language=PostgreSQL
+	stmtSelectSessionsByUserID, err = db.Prepare(`
+select
+	"session"."id",
+	"session"."user_agent",
+	"session"."created_at",
+	"session"."last_seen_at",
+	"session"."expires_at"
+from
+	"session"
+where
+	"session"."user_id" = $1 and
+	"session"."expires_at" > now()
+order by
+	"session"."last_seen_at" desc
+;   `)
+	err = errors.Wrap(err, "init16: ")
+	return
+}
+
+func (db *DB) SelectSessionsByUserID(userID UserID) (sessions []Session, err error) {
+	defer metrics.ObserveQuery("SelectSessionsByUserID", time.Now())
+	defer func() {
+		if err != nil {
+			err = errors.New("Error on exec 'SelectSessionsByUserID' statement: " + err.Error())
+		}
+	}()
+	rows, err := stmtSelectSessionsByUserID.Query(userID)
+	if err != nil {
+		return
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		session := Session{UserID: userID}
+		if err = rows.Scan(
+			&session.Id,
+			&session.UserAgent,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.ExpiresAt,
+		); err != nil {
+			return
+		}
+		sessions = append(sessions, session)
+	}
+	err = rows.Err()
+	return
+}
+
+var stmtDeleteSessionByIDAndUserID *sql.Stmt
+
+func (db *DB) init17() (err error) {
+	//language=PostgreSQL
+	stmtDeleteSessionByIDAndUserID, err = db.Prepare(`
+delete from
+	"session"
+where
+	"session"."id" = $1 and
+	"session"."user_id" = $2
+;   `)
+	err = errors.Wrap(err, "init17: ")
+	return
+}
+
+// userID не даёт завершить чужую сессию, подобрав её номер.
+func (db *DB) DeleteSessionByIDAndUserID(sessionID SessionID, userID UserID) (exist bool, err error) {
+	defer metrics.ObserveQuery("DeleteSessionByIDAndUserID", time.Now())
+	result, err := stmtDeleteSessionByIDAndUserID.Exec(sessionID, userID)
+	if err != nil {
+		err = errors.New("Error on exec 'DeleteSessionByIDAndUserID' statement: " + err.Error())
+		return
+	}
+	rowsAffected, _ := result.RowsAffected()
+	exist = rowsAffected != 0
+	return
+}
+
+var stmtDeleteExpiredSessions *sql.Stmt
+
+func (db *DB) init18() (err error) {
+	//language=PostgreSQL
+	stmtDeleteExpiredSessions, err = db.Prepare(`
+delete from
+	"session"
+where
+	"session"."user_id" = $1 and
+	"session"."expires_at" <= now()
+;   `)
+	err = errors.Wrap(err, "init18: ")
+	return
+}
+
+// вызывается при входе, что бы таблица не копила мёртвые сессии.
+func (db *DB) DeleteExpiredSessions(userID UserID) (err error) {
+	defer metrics.ObserveQuery("DeleteExpiredSessions", time.Now())
+	_, err = stmtDeleteExpiredSessions.Exec(userID)
+	if err != nil {
+		err = errors.New("Error on exec 'DeleteExpiredSessions' statement: " + err.Error())
+	}
+	return
+}
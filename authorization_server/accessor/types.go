@@ -1,9 +1,6 @@
 package accessor
 
-import (
-	"database/sql"
-	"time"
-)
+import "time"
 
 type UserID int32
 
@@ -11,7 +8,8 @@ type User struct {
 	Id         UserID // первичный ключ, через который связаны остальные поля.
 	Disposable bool   /* Играет ли пользователь просто так, без sms и регистрации (и попадания
 	   в таблицу рекордов). Такие пользователи создаются, когда входят в
-	   игру с одним только именем, и удаляются при выходе из партии. */
+	   игру с одним только именем, и удаляются при выходе или после долгого
+	   бездействия, если не станут обычными через /api/v1/user/upgrade. */
 	Login         string    // видимое другим игрокам имя пользователя
 	AvatarAddress string    // адрес относительно корня сайта: '/media/name-src32.ext'
 	LastLoginTime time.Time // timestamp
@@ -20,7 +18,7 @@ type User struct {
 type RegularLoginInformation struct {
 	// Id           int32
 	UserID       UserID
-	PasswordHash string // по алгоритму sha3
+	PasswordHash string // argon2id в формате PHC или, у старых пользователей, sha256 в hex
 }
 
 type GameStatistics struct {
@@ -30,13 +28,18 @@ type GameStatistics struct {
 	Wins        int   // количество доведённых до победного конца
 }
 
-// текущая принадлежность к игре.
-// допущение - только одна игра в один момент времени.
-type CurrentLogin struct {
-	// Id int32
-	UserId UserID
-	// токен авторицации, ставящийся как cookie пользователю
-	AuthorizationToken sql.NullString
-	// csrf token, проверяемый при приёме html форм при загрузке пользовательских данных.
-	CSRFToken sql.NullString
+type SessionID int32
+
+// сессия пользователя на одном устройстве.
+type Session struct {
+	Id     SessionID
+	UserID UserID
+	// заголовок User-Agent при входе.
+	UserAgent string
+	// повторяется клиентом в запросах, меняющих данные, см. handlers.validCSRFToken.
+	CSRFToken  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	// после этого момента токен не принимается.
+	ExpiresAt time.Time
 }
@@ -0,0 +1,120 @@
+// Обработка загруженных аватарок: проверка, что это действительно картинка,
+// уменьшение до фиксированных размеров и имена файлов по содержимому.
+
+package avatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	_ "image/gif" // регистрируют декодеры для image.Decode
+	_ "image/jpeg"
+	"image/png"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// размер загружаемого файла в байтах.
+const MaxUploadSize = 5 << 20
+
+// ограничение на ширину*высоту: маленький файл может распаковаться в гигабайты пикселей.
+const maxPixels = 25000000
+
+// стороны квадратных миниатюр. Первый размер - основной, его адрес хранится в "avatar_address",
+// остальные лежат рядом с тем же hash в имени: <hash>-64.png.
+var Sizes = []int{256, 64}
+
+var (
+	ErrTooLarge          = errors.New("avatar file is too large")
+	ErrUnsupportedFormat = errors.New("avatar is not a PNG, JPEG, GIF or WebP image")
+	ErrTooManyPixels     = errors.New("avatar dimensions are too large")
+)
+
+// готовый к записи файл миниатюры.
+type Image struct {
+	Size int
+	Name string // <hash>-<size>.png
+	Data []byte
+}
+
+var namePattern = regexp.MustCompile(`^([0-9a-f]{64})-[0-9]+\.png$`)
+
+func fileName(hash string, size int) string {
+	return hash + "-" + strconv.Itoa(size) + ".png"
+}
+
+// Имена всех миниатюр аватарки по имени основной, для удаления.
+// ok == false, если имя не из Process, например у аватарок, загруженных до появления миниатюр.
+func Siblings(name string) (names []string, ok bool) {
+	match := namePattern.FindStringSubmatch(name)
+	if match == nil {
+		return
+	}
+	for _, size := range Sizes {
+		names = append(names, fileName(match[1], size))
+	}
+	ok = true
+	return
+}
+
+// Декодирует загруженный файл, обрезает по центру до квадрата и уменьшает до Sizes.
+// Результат всегда PNG: он сохраняет прозрачность, и клиенту не нужно знать исходный формат.
+// Имена содержат sha256 исходного файла, поэтому одинаковые загрузки дают одинаковые файлы,
+// а новая аватарка всегда получает новый адрес и не застревает в кеше браузера.
+func Process(data []byte) (images []Image, err error) {
+	if len(data) > MaxUploadSize {
+		err = ErrTooLarge
+		return
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		err = ErrUnsupportedFormat
+		return
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		err = ErrTooManyPixels
+		return
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		err = ErrUnsupportedFormat
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	square := centerSquare(source.Bounds())
+	for _, size := range Sizes {
+		thumbnail := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), source, square, draw.Src, nil)
+		buffer := bytes.Buffer{}
+		err = png.Encode(&buffer, thumbnail)
+		if err != nil {
+			err = errors.Wrap(err, "png.Encode: ")
+			return
+		}
+		images = append(images, Image{
+			Size: size,
+			Name: fileName(hash, size),
+			Data: buffer.Bytes(),
+		})
+	}
+	return
+}
+
+// наибольший квадрат в центре прямоугольника.
+func centerSquare(bounds image.Rectangle) (square image.Rectangle) {
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	square = image.Rect(x, y, x+side, y+side)
+	return
+}
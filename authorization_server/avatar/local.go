@@ -0,0 +1,89 @@
+package avatar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Папка на диске, которую отдаёт Nginx. Сервер авторизации и Nginx должны видеть
+// её через общий volume.
+type LocalStorage struct {
+	Root string
+	// адрес, по которому Nginx отдаёт Root, например "/media/images/".
+	URLPrefix string
+}
+
+func NewLocalStorage(root string, urlPrefix string) (storage *LocalStorage) {
+	storage = &LocalStorage{
+		Root:      root,
+		URLPrefix: urlPrefix,
+	}
+	return
+}
+
+// данные пишутся во временный файл в той же папке и переименовываются.
+func (ls *LocalStorage) Put(name string, data []byte) (created bool, err error) {
+	path := filepath.Join(ls.Root, name)
+	if _, err = os.Stat(path); err == nil {
+		return
+	}
+	temporary, err := ioutil.TempFile(ls.Root, ".upload-")
+	if err != nil {
+		err = errors.Wrap(err, "ioutil.TempFile: ")
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(temporary.Name())
+		}
+	}()
+	_, err = temporary.Write(data)
+	if err == nil {
+		err = temporary.Sync()
+	}
+	closeErr := temporary.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temporary.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(temporary.Name(), path)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "write avatar '"+name+"': ")
+		return
+	}
+	created = true
+	return
+}
+
+func (ls *LocalStorage) Delete(name string) (err error) {
+	err = os.Remove(filepath.Join(ls.Root, name))
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	err = errors.Wrap(err, "remove avatar: ")
+	return
+}
+
+func (ls *LocalStorage) URL(name string) (address string) {
+	address = ls.URLPrefix + name
+	return
+}
+
+// Только имя без пути: у старых аватарок в имени был логин, и "../" в нём
+// не должен выводить из Root.
+func (ls *LocalStorage) Name(address string) (name string, ok bool) {
+	if !strings.HasPrefix(address, ls.URLPrefix) {
+		return
+	}
+	name = strings.TrimPrefix(address, ls.URLPrefix)
+	ok = name != "" && name == filepath.Base(name) && name != "." && name != ".."
+	return
+}
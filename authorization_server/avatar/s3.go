@@ -0,0 +1,183 @@
+package avatar
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// S3-совместимое хранилище: Amazon S3, MinIO, Yandex Object Storage и подобные.
+// Бакет должен отдавать объекты на чтение без подписи, их адреса попадают клиентам.
+// Запросы подписываются AWS Signature Version 4, адреса в стиле path: <Endpoint>/<Bucket>/<name>,
+// такой стиль понимают все совместимые реализации.
+type S3Storage struct {
+	Endpoint  string // "https://storage.yandexcloud.net", "http://minio:9000"
+	Region    string // "us-east-1", если хранилищу всё равно
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// адрес, по которому клиенты скачивают объекты, например CDN перед бакетом.
+	PublicURL string
+	Client    *http.Client
+}
+
+// publicURL пустой - объекты отдаются прямо из <endpoint>/<bucket>.
+func NewS3Storage(endpoint string, region string, bucket string, accessKey string, secretKey string, publicURL string) (storage *S3Storage) {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if publicURL == "" {
+		publicURL = endpoint + "/" + bucket
+	}
+	storage = &S3Storage{
+		Endpoint:  endpoint,
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		PublicURL: strings.TrimSuffix(publicURL, "/"),
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	return
+}
+
+// PUT в S3 атомарен: объект появляется только целиком.
+func (s3 *S3Storage) Put(name string, data []byte) (created bool, err error) {
+	response, err := s3.do(http.MethodHead, name, nil)
+	if err != nil {
+		err = errors.Wrap(err, "head avatar '"+name+"': ")
+		return
+	}
+	if response.StatusCode == http.StatusOK {
+		return
+	}
+	// без права s3:ListBucket S3 отвечает на HEAD отсутствующего объекта 403, а не 404.
+	// Ключ без прав на запись получит 403 и на PUT.
+	if response.StatusCode != http.StatusNotFound && response.StatusCode != http.StatusForbidden {
+		err = errors.New("head avatar '" + name + "': unexpected status " + response.Status)
+		return
+	}
+	response, err = s3.do(http.MethodPut, name, data)
+	if err == nil && response.StatusCode != http.StatusOK {
+		err = errors.New("unexpected status " + response.Status)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "put avatar '"+name+"': ")
+		return
+	}
+	created = true
+	return
+}
+
+// S3 отвечает 204 и на удаление несуществующего объекта.
+func (s3 *S3Storage) Delete(name string) (err error) {
+	response, err := s3.do(http.MethodDelete, name, nil)
+	if err == nil && response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK &&
+		response.StatusCode != http.StatusNotFound {
+		err = errors.New("unexpected status " + response.Status)
+	}
+	err = errors.Wrap(err, "delete avatar '"+name+"': ")
+	return
+}
+
+func (s3 *S3Storage) URL(name string) (address string) {
+	address = s3.PublicURL + "/" + name
+	return
+}
+
+func (s3 *S3Storage) Name(address string) (name string, ok bool) {
+	prefix := s3.PublicURL + "/"
+	if !strings.HasPrefix(address, prefix) {
+		return
+	}
+	name = strings.TrimPrefix(address, prefix)
+	ok = name != "" && !strings.Contains(name, "/")
+	return
+}
+
+// выполняет подписанный запрос к объекту name. Тело ответа прочитано и закрыто:
+// для этих запросов важен только код ответа.
+func (s3 *S3Storage) do(method string, name string, body []byte) (response *http.Response, err error) {
+	request, err := http.NewRequest(method, s3.Endpoint+"/"+s3.Bucket+"/"+url.PathEscape(name), bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	if method == http.MethodPut {
+		request.Header.Set("Content-Type", "image/png")
+		// имя по содержимому, файл под ним никогда не меняется.
+		request.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+		request.ContentLength = int64(len(body))
+	}
+	s3.sign(request, body, time.Now().UTC())
+	response, err = s3.Client.Do(request)
+	if err != nil {
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	_ = response.Body.Close()
+	return
+}
+
+// AWS Signature Version 4 с подписью заголовков:
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s3 *S3Storage) sign(request *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	payloadHex := hex.EncodeToString(payloadHash[:])
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHex)
+
+	// подписываются host и все x-amz-*, а так же content-type и cache-control, если есть.
+	headers := map[string]string{"host": request.URL.Host}
+	names := []string{"host"}
+	for _, name := range []string{"Cache-Control", "Content-Type", "X-Amz-Content-Sha256", "X-Amz-Date"} {
+		if value := request.Header.Get(name); value != "" {
+			lower := strings.ToLower(name)
+			headers[lower] = strings.TrimSpace(value)
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHex,
+	}, "\n")
+	scope := date + "/" + s3.Region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s3.SecretKey), date)
+	key = hmacSHA256(key, s3.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s3.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
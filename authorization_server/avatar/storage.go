@@ -0,0 +1,31 @@
+package avatar
+
+// Хранилище файлов аватарок. Адрес, который попадает в "avatar_address",
+// строит само хранилище: у папки за Nginx и у S3 адреса разные.
+type Storage interface {
+	// записывает файл целиком: по имени виден либо старый файл, либо новый.
+	// created == false, если файл с этим именем уже был: имя по содержимому, перезаписывать нечего.
+	Put(name string, data []byte) (created bool, err error)
+	// удаляет файл, отсутствие файла не ошибка.
+	Delete(name string) (err error)
+	// адрес файла для клиента.
+	URL(name string) (address string)
+	// имя файла по адресу из базы. ok == false, если адрес не из этого хранилища,
+	// например у аватарки по умолчанию: такие файлы не удаляются.
+	Name(address string) (name string, ok bool)
+}
+
+// Удаляет файл аватарки и все её миниатюры.
+func Delete(storage Storage, name string) (err error) {
+	names, ok := Siblings(name)
+	if !ok {
+		names = []string{name}
+	}
+	for _, sibling := range names {
+		deleteErr := storage.Delete(sibling)
+		if deleteErr != nil && err == nil {
+			err = deleteErr
+		}
+	}
+	return
+}
@@ -1,16 +1,42 @@
 package environment
 
 import (
+	"time"
+
 	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/avatar"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/limiter"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/oauth"
 )
 
 type Environment struct {
 	DB     accessor.DB
 	Config Config
+	// попытки входа и регистрации с одного адреса.
+	IPLimiter limiter.Limiter
+	// попытки входа в один аккаунт, блокирует его после череды неверных паролей.
+	LoginLimiter limiter.Limiter
+	// файлы аватарок: папка за Nginx или S3, по --avatar-storage.
+	AvatarStorage avatar.Storage
+	// провайдеры входа по имени из ?provider=, только настроенные флагами.
+	OAuthProviders map[string]oauth.Provider
 }
 
 type Config struct {
 	PostgresPath  *string
 	ListeningPort *string
 	ImagesRoot    *string
+	ImagesURL     *string
+	// сколько живёт сессия без запросов, каждый запрос продлевает её на этот срок.
+	SessionLifetime *time.Duration
+	// одноразовый пользователь удаляется, если от него не было запросов дольше этого срока.
+	DisposableUserTTL *time.Duration
+	// заголовок с адресом клиента, который ставит Nginx. Пустой - адрес соединения.
+	ClientIPHeader *string
+	// адрес /api/v1/oauth/callback снаружи, зарегистрированный у провайдеров.
+	OAuthRedirectURL *string
+	// куда попадает пользователь после входа через провайдера.
+	OAuthSuccessURL *string
+	// токен игровых серверов для /api/v1/internal/, пустой - запросы отклоняются.
+	InternalToken *string
 }
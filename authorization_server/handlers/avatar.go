@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/avatar"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
+)
+
+// запас на заголовки multipart и остальные поля формы.
+const multipartOverhead = 64 << 10
+
+// SetAvatar godoc
+// @Summary Upload user avatar.
+// @Description Upload avatar from \<form enctype='multipart/form-data' action='/api/v1/avatar'>\<input type="file" name="avatar"></form>. PNG, JPEG, GIF or WebP up to 5 MiB, stored as square PNG thumbnails.
+// @Tags avatar
+// @Accept multipart/form-data
+// @Produce application/json
+// @Success 201 {object} types.ServerResponse
+// @Failure 400 {object} types.ServerResponse
+// @Failure 403 {object} types.ServerResponse
+// @Failure 413 {object} types.ServerResponse
+// @Failure 415 {object} types.ServerResponse
+// @Failure 422 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/avatar [post]
+func (e *Environment) SetAvatar(w http.ResponseWriter, r *http.Request) {
+	defer func() { _ = r.Body.Close() }()
+	w.Header().Set("Content-Type", "application/json")
+	// до authorizedUser: проверка CSRF может прочитать форму из тела.
+	r.Body = http.MaxBytesReader(w, r.Body, avatar.MaxUploadSize+multipartOverhead)
+	user, ok := e.authorizedUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseMultipartForm(1 << 20)
+	if err != nil {
+		logging.FromRequest(r).Info().Err(err).Msg("invalid_multipart_form")
+		status, message := http.StatusBadRequest, "invalid_multipart_form"
+		if strings.Contains(err.Error(), "request body too large") {
+			status, message = http.StatusRequestEntityTooLarge, "avatar_too_large"
+		}
+		w.WriteHeader(status)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(status),
+			Message: message,
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		logging.FromRequest(r).Info().Err(err).Msg("cannot_get_file")
+		w.WriteHeader(http.StatusBadRequest)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusBadRequest),
+			Message: "cannot_get_file",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	data, err := ioutil.ReadAll(file)
+	_ = file.Close()
+	if err == nil {
+		var images []avatar.Image
+		images, err = avatar.Process(data)
+		if err == nil {
+			e.saveAvatar(w, r, user.Login, user.AvatarAddress, images)
+			return
+		}
+	}
+
+	// расширение и Content-Type от клиента не важны, формат определяется по содержимому.
+	status, message := http.StatusBadRequest, "cannot_get_file"
+	switch err {
+	case avatar.ErrTooLarge:
+		status, message = http.StatusRequestEntityTooLarge, "avatar_too_large"
+	case avatar.ErrUnsupportedFormat:
+		status, message = http.StatusUnsupportedMediaType, "unsupported_image_format"
+	case avatar.ErrTooManyPixels:
+		status, message = http.StatusUnprocessableEntity, "image_dimensions_too_large"
+	}
+	logging.FromRequest(r).Info().Err(err).Msg(message)
+	w.WriteHeader(status)
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(status),
+		Message: message,
+	}.MarshalJSON()
+	_, _ = w.Write(response)
+}
+
+// Файлы пишутся до обновления базы: адрес в базе всегда указывает на записанный файл.
+// Если база не обновилась, новые файлы удаляются, старая аватарка остаётся.
+// После обновления удаляется старая аватарка, если она больше ни у кого не стоит.
+func (e *Environment) saveAvatar(w http.ResponseWriter, r *http.Request, login string, previousAddress string, images []avatar.Image) {
+	var created []string
+	removeCreated := func() {
+		for _, name := range created {
+			if err := e.AvatarStorage.Delete(name); err != nil {
+				logging.FromRequest(r).Warn().Err(err).Msg("avatar_removal_error")
+			}
+		}
+	}
+	for _, image := range images {
+		isNew, err := e.AvatarStorage.Put(image.Name, image.Data)
+		if err != nil {
+			removeCreated()
+			logging.FromRequest(r).Error().Err(err).Msg("cannot_create_file")
+			w.WriteHeader(http.StatusInternalServerError)
+			response, _ := types.ServerResponse{
+				Status:  http.StatusText(http.StatusInternalServerError),
+				Message: "cannot_create_file",
+			}.MarshalJSON()
+			_, _ = w.Write(response)
+			return
+		}
+		if isNew {
+			created = append(created, image.Name)
+		}
+	}
+
+	address := e.AvatarStorage.URL(images[0].Name)
+	err := e.DB.UpdateUsersAvatarByLogin(login, address)
+	if err != nil {
+		removeCreated()
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	if previousAddress != address {
+		e.removeAvatar(r, previousAddress)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(http.StatusCreated),
+		Message: "successful_avatar_uploading",
+	}.MarshalJSON()
+	_, _ = w.Write(response)
+}
+
+// удаляет файлы аватарки, если она загружена пользователем и больше ни у кого не стоит.
+// Аватарка по умолчанию лежит не в хранилище и не удаляется.
+// Ошибка только пишется в лог: пользователю она уже не мешает.
+func (e *Environment) removeAvatar(r *http.Request, address string) {
+	name, ok := e.AvatarStorage.Name(address)
+	if !ok {
+		return
+	}
+	used, err := e.DB.SelectAvatarAddressIsUsed(address)
+	if err == nil && !used {
+		err = avatar.Delete(e.AvatarStorage, name)
+	}
+	if err != nil {
+		logging.FromRequest(r).Warn().Err(err).Str("avatar", address).Msg("avatar_removal_error")
+	}
+	return
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
+)
+
+// проверяет, что расстановку примет метод "upload_map" игрового сервера:
+// только известное оружие и ровно один флаг.
+// Возвращает ключ сообщения об ошибке или пустую строку.
+func checkFormation(formation types.Formation) (message string) {
+	if formation.Name == "" {
+		message = "empty_formation_name"
+		return
+	}
+	numberOfFlags := 0
+	for _, weapon := range formation.Weapons {
+		switch weapon {
+		case "rock", "scissors", "paper":
+		case "flag":
+			numberOfFlags++
+		default:
+			message = "unknown_weapon"
+			return
+		}
+	}
+	if numberOfFlags != 1 {
+		message = "formation_must_contain_exactly_one_flag"
+	}
+	return
+}
+
+// Formations godoc
+// @Summary Get saved formations.
+// @Description Return named weapon arrangements of the current user, ready to be sent to the game server as "upload_map".
+// @Tags formations
+// @Accept application/json
+// @Produce application/json
+// @Success 200 {array} types.Formation
+// @Failure 403 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/formations [get]
+func (e *Environment) Formations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = r.Body.Close()
+
+	user, ok := e.authorizedUser(w, r)
+	if !ok {
+		return
+	}
+
+	formations, err := e.DB.SelectFormationsByUserID(user.Id)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response, _ := formations.MarshalJSON()
+	_, _ = w.Write(response)
+}
+
+// SaveFormation godoc
+// @Summary Save formation.
+// @Description Save named weapon arrangement, formation with the same name is overwritten.
+// @Tags formations
+// @Accept application/json
+// @Produce application/json
+// @Param formation body types.Formation true "name weapons"
+// @Success 201 {object} types.ServerResponse
+// @Failure 400 {object} types.ServerResponse
+// @Failure 403 {object} types.ServerResponse
+// @Failure 422 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/formations [post]
+func (e *Environment) SaveFormation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	_ = r.Body.Close()
+
+	user, ok := e.authorizedUser(w, r)
+	if !ok {
+		return
+	}
+
+	formation := types.Formation{}
+	err = formation.UnmarshalJSON(bodyBytes)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusBadRequest),
+			Message: "invalid_request_format",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	if message := checkFormation(formation); message != "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusUnprocessableEntity),
+			Message: message,
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	err = e.DB.UpsertIntoFormation(user.Id, formation)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(http.StatusCreated),
+		Message: "successful_formation_saving",
+	}.MarshalJSON()
+	_, _ = w.Write(response)
+}
+
+// DeleteFormation godoc
+// @Summary Delete formation.
+// @Description Delete named weapon arrangement of the current user.
+// @Tags formations
+// @Accept application/json
+// @Produce application/json
+// @Param name query string true "formation name"
+// @Success 200 {object} types.ServerResponse
+// @Failure 403 {object} types.ServerResponse
+// @Failure 404 {object} types.ServerResponse
+// @Failure 422 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/formations [delete]
+func (e *Environment) DeleteFormation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = r.Body.Close()
+
+	user, ok := e.authorizedUser(w, r)
+	if !ok {
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusUnprocessableEntity),
+			Message: "empty_formation_name",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	exist, err := e.DB.DeleteFromFormation(user.Id, name)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	if !exist {
+		w.WriteHeader(http.StatusNotFound)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusNotFound),
+			Message: "formation_not_found",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "successful_formation_removal",
+	}.MarshalJSON()
+	_, _ = w.Write(response)
+}
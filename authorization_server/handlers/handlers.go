@@ -7,51 +7,71 @@
 package handlers
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"fmt"
-	"io"
 	"io/ioutil"
-	"log"
-	"math/rand"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
-	"time"
 
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
 	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/environment"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/password"
 	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
 )
 
 // прикрепляем функции с логикой к глобальному окружению, обеспечивая доступ к конфигу и базе данных
 type Environment environment.Environment
 
-func sha256hash(password string) string {
-	hasher := sha256.New()
-	hasher.Write([]byte(password))
-	return hex.EncodeToString(hasher.Sum(nil))
-}
+// хеш, с которым сверяется пароль несуществующего пользователя: ответ занимает столько же
+// времени, сколько и для существующего, и по нему нельзя узнать, занят ли логин.
+var dummyPasswordHash, _ = password.Hash("dummy password")
+
+const defaultAvatarURL = "/images/default.png"
 
-func init() {
-	rand.Seed(time.Now().Unix())
+// находит пользователя по cookie SessionId, для запросов, меняющих данные, проверяет токен CSRF.
+// Если пользователь не авторизован, сама отправляет ответ с ошибкой, вызывающему остаётся только выйти.
+func (e *Environment) authorizedUser(w http.ResponseWriter, r *http.Request) (user accessor.User, ok bool) {
+	user, _, ok = e.authorizedSession(w, r)
+	return
 }
 
-func randomToken() string {
-	cookieChars := []byte("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz+_")
-	result := make([]byte, 20)
-	for i := 0; i < 20; {
-		key := rand.Uint64()
-		for j := 0; j < 10; i, j = i+1, j+1 {
-			result[i] = cookieChars[key&63]
-			key >>= 6
-		}
+// как authorizedUser, но возвращает и сессию, из которой пришёл запрос.
+func (e *Environment) authorizedSession(w http.ResponseWriter, r *http.Request) (user accessor.User, session accessor.Session, ok bool) {
+	exist, user, session, err := e.currentSession(w, r)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
 	}
-	return string(result)
+	if !exist {
+		w.WriteHeader(http.StatusForbidden)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusForbidden),
+			Message: "unauthorized_user",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	logging.SetLogin(r, user.Login)
+	if !isSafeMethod(r.Method) && !validCSRFToken(r, session) {
+		logging.FromRequest(r).Warn().Msg("invalid_csrf_token")
+		w.WriteHeader(http.StatusForbidden)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusForbidden),
+			Message: "invalid_csrf_token",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	ok = true
+	return
 }
 
-const defaultAvatarURL = "/images/default.png"
-
 // RegistrationRegular godoc
 // @Summary Regular user registration.
 // @Description Registrate users with password and statistics.
@@ -82,6 +102,10 @@ func (e *Environment) RegistrationRegular(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if !e.allowAttempt(w, r, "") {
+		return
+	}
+
 	if registrationInfo.Login == "" {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		response, _ := types.ServerResponse{
@@ -102,6 +126,18 @@ func (e *Environment) RegistrationRegular(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	passwordHash, err := password.Hash(registrationInfo.Password)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("password_hashing_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "password_hashing_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
 	userID, isDuplicate, err := e.DB.InsertIntoUser(registrationInfo.Login, defaultAvatarURL, false)
 	if isDuplicate {
 		w.WriteHeader(http.StatusConflict)
@@ -122,9 +158,9 @@ func (e *Environment) RegistrationRegular(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = e.DB.InsertIntoRegularLoginInformation(userID, sha256hash(registrationInfo.Password))
+	err = e.DB.InsertIntoRegularLoginInformation(userID, passwordHash)
 	if err != nil {
-		log.Print(err)
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
 		w.WriteHeader(http.StatusInternalServerError)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusInternalServerError),
@@ -136,7 +172,7 @@ func (e *Environment) RegistrationRegular(w http.ResponseWriter, r *http.Request
 
 	err = e.DB.InsertIntoGameStatistics(userID, 0, 0)
 	if err != nil {
-		log.Print(err)
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
 		w.WriteHeader(http.StatusInternalServerError)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusInternalServerError),
@@ -146,29 +182,10 @@ func (e *Environment) RegistrationRegular(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// создаём токены авторизации.
-	authorizationToken := randomToken()
-	err = e.DB.UpsertIntoCurrentLogin(userID, authorizationToken)
-	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		response, _ := types.ServerResponse{
-			Status:  http.StatusText(http.StatusInternalServerError),
-			Message: "database_error",
-		}.MarshalJSON()
-		_, _ = w.Write(response)
+	// создаём сессию.
+	if !e.startSession(w, r, userID) {
 		return
 	}
-	// Отсылаем нормальный ответ.
-	http.SetCookie(w, &http.Cookie{
-		Name:     "SessionId",
-		Value:    authorizationToken,
-		Path:     "/",
-		Expires:  time.Now().AddDate(0, 0, 7),
-		Secure:   true,
-		HttpOnly: true,
-		// SameSite: http.SameSiteLaxMode,
-	})
 	w.WriteHeader(http.StatusCreated)
 
 	response, _ := types.ServerResponse{
@@ -176,6 +193,7 @@ func (e *Environment) RegistrationRegular(w http.ResponseWriter, r *http.Request
 		Message: "successful_reusable_registration",
 	}.MarshalJSON()
 	_, _ = w.Write(response)
+	metrics.Registrations.WithLabelValues("regular").Inc()
 }
 
 // RegistrationTemporary godoc
@@ -205,6 +223,10 @@ func (e *Environment) RegistrationTemporary(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if !e.allowAttempt(w, r, "") {
+		return
+	}
+
 	if registrationInfo.Login == "" {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		response, _ := types.ServerResponse{
@@ -226,7 +248,7 @@ func (e *Environment) RegistrationTemporary(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	if err != nil {
-		log.Print(err)
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
 		w.WriteHeader(http.StatusInternalServerError)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusInternalServerError),
@@ -236,35 +258,17 @@ func (e *Environment) RegistrationTemporary(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// создаём токены авторизации.
-	authorizationToken := randomToken()
-	err = e.DB.UpsertIntoCurrentLogin(userID, authorizationToken)
-	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		response, _ := types.ServerResponse{
-			Status:  http.StatusText(http.StatusInternalServerError),
-			Message: "database_error",
-		}.MarshalJSON()
-		_, _ = w.Write(response)
+	// создаём сессию.
+	if !e.startSession(w, r, userID) {
 		return
 	}
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "SessionId",
-		Value:    authorizationToken,
-		Path:     "/",
-		Expires:  time.Now().AddDate(0, 0, 7),
-		Secure:   true,
-		HttpOnly: true,
-		// SameSite: http.SameSiteLaxMode,
-	})
 	w.WriteHeader(http.StatusCreated)
 	response, _ := types.ServerResponse{
 		Status:  http.StatusText(http.StatusCreated),
 		Message: "successful_disposable_registration",
 	}.MarshalJSON()
 	_, _ = w.Write(response)
+	metrics.Registrations.WithLabelValues("temporary").Inc()
 }
 
 // LeaderBoard godoc
@@ -302,7 +306,7 @@ func (e *Environment) LeaderBoard(w http.ResponseWriter, r *http.Request) {
 
 	LeaderBoard, err := e.DB.SelectLeaderBoard(limit, offset)
 	if err != nil {
-		log.Print(err)
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
 		w.WriteHeader(http.StatusInternalServerError)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusInternalServerError),
@@ -367,7 +371,7 @@ func (e *Environment) UserProfile(w http.ResponseWriter, r *http.Request) {
 
 	userProfile, err := e.DB.SelectUserByLogin(login)
 	if err != nil {
-		log.Print(err)
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
 		w.WriteHeader(http.StatusInternalServerError)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusInternalServerError),
@@ -411,9 +415,13 @@ func (e *Environment) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	exists, userId, err := e.DB.SelectUserIdByLoginPasswordHash(registrationInfo.Login, sha256hash(registrationInfo.Password))
+	if !e.allowAttempt(w, r, loginAccount(registrationInfo.Login)) {
+		return
+	}
+
+	exists, loginInformation, err := e.DB.SelectRegularLoginInformationByLogin(registrationInfo.Login)
 	if err != nil {
-		log.Print(err)
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
 		w.WriteHeader(http.StatusInternalServerError)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusInternalServerError),
@@ -422,45 +430,57 @@ func (e *Environment) Login(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write(response)
 		return
 	}
-	if exists {
-		authorizationToken := randomToken()
-		err = e.DB.UpsertIntoCurrentLogin(userId, authorizationToken)
-		if err != nil {
-			log.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			response, _ := types.ServerResponse{
-				Status:  http.StatusText(http.StatusInternalServerError),
-				Message: "database_error",
-			}.MarshalJSON()
-			_, _ = w.Write(response)
+	if !exists {
+		loginInformation.PasswordHash = dummyPasswordHash
+	}
+	match, needsRehash, err := password.Verify(registrationInfo.Password, loginInformation.PasswordHash)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Int32("user_id", int32(loginInformation.UserID)).Msg("invalid_password_hash")
+	}
+	if exists && match {
+		e.attemptSucceeded(r, loginAccount(registrationInfo.Login))
+		if needsRehash {
+			e.rehashPassword(r, loginInformation.UserID, registrationInfo.Password)
+		}
+		if !e.startSession(w, r, loginInformation.UserID) {
 			return
 		}
 		// Уже нормальный ответ отсылаем.
-		http.SetCookie(w, &http.Cookie{
-			Name:     "SessionId",
-			Value:    authorizationToken,
-			Path:     "/",
-			Expires:  time.Now().AddDate(0, 0, 7),
-			Secure:   true,
-			HttpOnly: true,
-			// SameSite: http.SameSiteLaxMode,
-		})
 		w.WriteHeader(http.StatusAccepted)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusAccepted),
 			Message: "successful_password_login",
 		}.MarshalJSON()
 		_, _ = w.Write(response)
+		metrics.Logins.WithLabelValues("success").Inc()
+		logging.SetLogin(r, registrationInfo.Login)
 	} else {
+		e.attemptFailed(r, loginAccount(registrationInfo.Login))
 		w.WriteHeader(http.StatusForbidden)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusFailedDependency),
 			Message: "wrong_login_or_password",
 		}.MarshalJSON()
 		_, _ = w.Write(response)
+		metrics.Logins.WithLabelValues("wrong_login_or_password").Inc()
 	}
 }
 
+// заменяет устаревший хеш пароля (sha256 без соли или argon2id со старыми параметрами)
+// на хеш с текущими параметрами. Ошибка не мешает входу: хеш обновится при следующем.
+func (e *Environment) rehashPassword(r *http.Request, userID accessor.UserID, plainPassword string) {
+	passwordHash, err := password.Hash(plainPassword)
+	if err == nil {
+		err = e.DB.UpdateRegularLoginInformationPasswordHash(userID, passwordHash)
+	}
+	if err != nil {
+		logging.FromRequest(r).Warn().Err(err).Int32("user_id", int32(userID)).Msg("password_rehash_error")
+		return
+	}
+	logging.FromRequest(r).Info().Int32("user_id", int32(userID)).Msg("password_rehashed")
+	return
+}
+
 // Logout godoc
 // @Summary Log registered user out.
 // @Description Delete cookie in client and database.
@@ -475,7 +495,7 @@ func (e *Environment) Logout(w http.ResponseWriter, r *http.Request) {
 	//get sid from cookies
 	inCookie, err := r.Cookie("SessionId")
 	if err != nil {
-		log.Print(err)
+		logging.FromRequest(r).Info().Err(err).Msg("unauthorized_user")
 		w.WriteHeader(http.StatusUnauthorized)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusUnauthorized),
@@ -485,135 +505,55 @@ func (e *Environment) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = e.DB.DropUsersSession(inCookie.Value)
-	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusNotFound)
-		response, _ := types.ServerResponse{
-			Status:  http.StatusText(http.StatusNotFound),
-			Message: "target_session_not_found",
-		}.MarshalJSON()
-		_, _ = w.Write(response)
-		return
-	}
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "SessionId",
-		Expires:  time.Unix(0, 0),
-		Secure:   true,
-		HttpOnly: true,
-		// SameSite: http.SameSiteLaxMode,
-	})
-
-	w.WriteHeader(http.StatusOK)
-	response, _ := types.ServerResponse{
-		Status:  http.StatusText(http.StatusOK),
-		Message: "successful_logout",
-	}.MarshalJSON()
-	_, _ = w.Write(response)
-}
-
-// Logout godoc
-// @Summary Upload user avatar.
-// @Description Upload avatar from \<form enctype='multipart/form-data' action='/api/v1/avatar'>\<input type="file" name="avatar"></form>.
-// @Tags avatar
-// @Accept multipart/form-data
-// @Produce application/json
-// @Success 201 {object} types.ServerResponse
-// @Failure 400 {object} types.ServerResponse
-// @Failure 401 {object} types.ServerResponse
-// @Failure 500 {object} types.ServerResponse
-// @Router /api/v1/avatar [post]
-func (e *Environment) SetAvatar(w http.ResponseWriter, r *http.Request) {
-	defer func() { _ = r.Body.Close() }()
-	w.Header().Set("Content-Type", "application/json")
-	//get SessionId from cookies
-	cookie, err := r.Cookie("SessionId")
-	if err != nil || cookie.Value == "" {
-		log.Print(err)
-		w.WriteHeader(http.StatusForbidden)
-		response, _ := types.ServerResponse{
-			Status:  http.StatusText(http.StatusForbidden),
-			Message: "unauthorized_user",
-		}.MarshalJSON()
-		_, _ = w.Write(response)
-		return
-	}
-
-	exist, user, err := e.DB.SelectUserBySessionId(cookie.Value)
+	// иначе чужой сайт может разлогинить пользователя.
+	exist, _, session, err := e.currentSession(w, r)
 	if err != nil {
-		log.Print(err)
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
 		w.WriteHeader(http.StatusInternalServerError)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusInternalServerError),
-			Message: "cannot_create_file",
+			Message: "database_error",
 		}.MarshalJSON()
 		_, _ = w.Write(response)
 		return
 	}
-	if !exist {
+	if exist && !validCSRFToken(r, session) {
+		logging.FromRequest(r).Warn().Msg("invalid_csrf_token")
 		w.WriteHeader(http.StatusForbidden)
 		response, _ := types.ServerResponse{
 			Status:  http.StatusText(http.StatusForbidden),
-			Message: "unauthorized_user",
+			Message: "invalid_csrf_token",
 		}.MarshalJSON()
 		_, _ = w.Write(response)
 		return
 	}
 
-	err = r.ParseMultipartForm(0)
-	if err != nil {
-		log.Print("handlers SetAvatar ParseMultipartForm: " + err.Error())
-		return
-	}
-
-	file, handler, err := r.FormFile("avatar")
-	if err != nil {
-		fmt.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
-		response, _ := types.ServerResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: "cannot_get_file",
-		}.MarshalJSON()
-		_, _ = w.Write(response)
-		return
+	// одноразовый пользователь уходит насовсем, его сессии удаляются вместе с ним.
+	tokenHash := hashSessionToken(inCookie.Value)
+	deleted, err := e.DB.DeleteDisposableUserBySessionTokenHash(tokenHash)
+	if err == nil && !deleted {
+		err = e.DB.DeleteSessionByTokenHash(tokenHash)
 	}
-
-	defer func() { _ = file.Close() }()
-	// /var/www/media/images/login.jpeg
-	fileName := user.Login + filepath.Ext(handler.Filename)
-	f, err := os.Create(*e.Config.ImagesRoot + "/" + fileName)
-	if err != nil {
-		fmt.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		response, _ := types.ServerResponse{
-			Status:  http.StatusText(http.StatusInternalServerError),
-			Message: "cannot_create_file",
-		}.MarshalJSON()
-		_, _ = w.Write(response)
-		return
+	if deleted {
+		metrics.DisposableUsersDeleted.WithLabelValues("logout").Inc()
 	}
-	defer func() { _ = f.Close() }()
-
-	//put avatar path to db
-	err = e.DB.UpdateUsersAvatarByLogin(user.Login, "/media/images/"+fileName)
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromRequest(r).Info().Err(err).Msg("target_session_not_found")
+		w.WriteHeader(http.StatusNotFound)
 		response, _ := types.ServerResponse{
-			Status:  http.StatusText(http.StatusInternalServerError),
-			Message: "database_error",
+			Status:  http.StatusText(http.StatusNotFound),
+			Message: "target_session_not_found",
 		}.MarshalJSON()
 		_, _ = w.Write(response)
 		return
 	}
 
-	_, _ = io.Copy(f, file)
+	clearSessionCookies(w)
 
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusOK)
 	response, _ := types.ServerResponse{
-		Status:  http.StatusText(http.StatusCreated),
-		Message: "successful_avatar_uploading",
+		Status:  http.StatusText(http.StatusOK),
+		Message: "successful_logout",
 	}.MarshalJSON()
 	_, _ = w.Write(response)
 }
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
+)
+
+// Запросы игровых серверов, требуют --internal-token, общий с игровыми серверами.
+
+// GameEnded godoc
+// @Summary Game server reports finished game.
+// @Description Delete disposable users of the given sessions: they were created for this one game. Regular users are not changed. Requires "Authorization: Bearer <internal token>".
+// @Tags internal
+// @Accept application/json
+// @Produce application/json
+// @Param game body types.GameEnded true "sha256 of session tokens in hex"
+// @Success 200 {object} types.ServerResponse
+// @Failure 400 {object} types.ServerResponse
+// @Failure 403 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/internal/game_ended [post]
+func (e *Environment) GameEnded(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	_ = r.Body.Close()
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if *e.Config.InternalToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(*e.Config.InternalToken)) != 1 {
+		logging.FromRequest(r).Warn().Msg("invalid_internal_token")
+		w.WriteHeader(http.StatusForbidden)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusForbidden),
+			Message: "invalid_internal_token",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	game := types.GameEnded{}
+	err = game.UnmarshalJSON(bodyBytes)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusBadRequest),
+			Message: "invalid_request_format",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	deletedUsers := 0
+	for _, tokenHash := range game.SessionTokenHashes {
+		deleted, err := e.DB.DeleteDisposableUserBySessionTokenHash(tokenHash)
+		if err != nil {
+			logging.FromRequest(r).Error().Err(err).Msg("database_error")
+			w.WriteHeader(http.StatusInternalServerError)
+			response, _ := types.ServerResponse{
+				Status:  http.StatusText(http.StatusInternalServerError),
+				Message: "database_error",
+			}.MarshalJSON()
+			_, _ = w.Write(response)
+			return
+		}
+		if deleted {
+			deletedUsers++
+		}
+	}
+	if deletedUsers != 0 {
+		metrics.DisposableUsersDeleted.WithLabelValues("game_end").Add(float64(deletedUsers))
+		logging.FromRequest(r).Info().Int("deleted", deletedUsers).Msg("disposable_users_deleted")
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "successful_game_end",
+	}.MarshalJSON()
+	_, _ = w.Write(response)
+}
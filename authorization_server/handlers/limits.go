@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/limiter"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
+)
+
+// адрес клиента: за Nginx адрес соединения у всех один, поэтому берётся заголовок.
+func (e *Environment) clientIP(r *http.Request) string {
+	if *e.Config.ClientIPHeader != "" {
+		if ip := strings.TrimSpace(r.Header.Get(*e.Config.ClientIPHeader)); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ключ аккаунта в LoginLimiter при входе по логину.
+func loginAccount(login string) string {
+	return "login:" + login
+}
+
+// ключ аккаунта в LoginLimiter для запросов вошедшего пользователя. Логин можно сменить
+// тем же запросом, поэтому ключом служит неизменный id, иначе смена логина сбрасывала бы паузу.
+func userAccount(userID accessor.UserID) string {
+	return "user:" + strconv.Itoa(int(userID))
+}
+
+// проверяет ограничения адреса клиента и, если account не пустой, аккаунта.
+// Если попытку нельзя делать сейчас, сама отвечает 429 с Retry-After, вызывающему остаётся только выйти.
+// Ошибка хранилища ограничений не должна закрывать вход, поэтому только пишется в лог.
+func (e *Environment) allowAttempt(w http.ResponseWriter, r *http.Request, account string) (ok bool) {
+	checks := []struct {
+		name    string
+		limiter limiter.Limiter
+		key     string
+		message string
+	}{
+		{"ip", e.IPLimiter, "ip:" + e.clientIP(r), "too_many_attempts"},
+		{"login", e.LoginLimiter, account, "login_temporarily_locked"},
+	}
+	for _, check := range checks {
+		if check.name == "login" && account == "" {
+			continue
+		}
+		allowed, retryAfter, err := check.limiter.Allow(check.key)
+		if err != nil {
+			logging.FromRequest(r).Warn().Err(err).Str("limiter", check.name).Msg("limiter_error")
+			continue
+		}
+		if allowed {
+			continue
+		}
+		logging.FromRequest(r).Info().Str("limiter", check.name).Dur("retry_after", retryAfter).Msg(check.message)
+		metrics.RateLimited.WithLabelValues(check.name).Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusTooManyRequests),
+			Message: check.message,
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	ok = true
+	return
+}
+
+// неверный пароль: пауза растёт и для адреса, и для аккаунта,
+// аккаунт блокируется после --lockout-threshold неудач подряд.
+func (e *Environment) attemptFailed(r *http.Request, account string) {
+	err := e.IPLimiter.Failure("ip:" + e.clientIP(r))
+	if err == nil {
+		err = e.LoginLimiter.Failure(account)
+	}
+	if err != nil {
+		logging.FromRequest(r).Warn().Err(err).Msg("limiter_error")
+	}
+	return
+}
+
+// удачный вход снимает паузу аккаунта. Адрес не сбрасывается: иначе перебирающий
+// чужие пароли сбрасывал бы его, входя в свой аккаунт.
+func (e *Environment) attemptSucceeded(r *http.Request, account string) {
+	err := e.LoginLimiter.Reset(account)
+	if err != nil {
+		logging.FromRequest(r).Warn().Err(err).Msg("limiter_error")
+	}
+	return
+}
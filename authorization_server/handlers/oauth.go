@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/oauth"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
+)
+
+// сколько пользователь может провести на странице провайдера.
+const oauthStateLifetime = 10 * time.Minute
+
+// попытки подобрать свободный логин для нового пользователя, см. createExternalUser.
+const externalLoginAttempts = 5
+
+// OAuthLogin godoc
+// @Summary Login with external provider.
+// @Description Redirect to the login page of the provider: 'vk' or configured OpenID Connect one. A logged in regular user gets the provider account linked instead.
+// @Tags oauth
+// @Param provider query string true "provider name"
+// @Success 302
+// @Failure 404 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/oauth/login [get]
+func (e *Environment) OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	_ = r.Body.Close()
+	name := r.URL.Query().Get("provider")
+	provider, ok := e.OAuthProviders[name]
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusNotFound),
+			Message: "unknown_oauth_provider",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	state, err := newRandomToken()
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("session_token_generation_error")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "session_token_generation_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	// state в cookie привязывает ответ провайдера к этому браузеру: иначе чужой код
+	// в ссылке залогинил бы жертву в аккаунт атакующего.
+	// Lax: cookie приходит при возврате с сайта провайдера, это переход по ссылке.
+	http.SetCookie(w, &http.Cookie{
+		Name:     "OAuthState",
+		Value:    name + ":" + state,
+		Path:     "/api/v1/oauth/",
+		MaxAge:   int(oauthStateLifetime / time.Second),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, provider.AuthCodeURL(state, *e.Config.OAuthRedirectURL), http.StatusFound)
+}
+
+// OAuthCallback godoc
+// @Summary Return from external provider.
+// @Description The provider redirects here with code and state. Logs in the user linked with the provider account, links it to the logged in regular user or creates a new user, then redirects to --oauth-success-url.
+// @Tags oauth
+// @Param code query string true "authorization code"
+// @Param state query string true "state from /api/v1/oauth/login"
+// @Success 302
+// @Failure 403 {object} types.ServerResponse
+// @Failure 409 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Failure 502 {object} types.ServerResponse
+// @Router /api/v1/oauth/callback [get]
+func (e *Environment) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	_ = r.Body.Close()
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	// state одноразовый.
+	http.SetCookie(w, &http.Cookie{
+		Name:     "OAuthState",
+		Path:     "/api/v1/oauth/",
+		Expires:  time.Unix(0, 0),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	var name, state string
+	if cookie, err := r.Cookie("OAuthState"); err == nil {
+		parts := strings.SplitN(cookie.Value, ":", 2)
+		if len(parts) == 2 {
+			name, state = parts[0], parts[1]
+		}
+	}
+	provider, ok := e.OAuthProviders[name]
+	if !ok || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		logging.FromRequest(r).Warn().Msg("invalid_oauth_state")
+		w.WriteHeader(http.StatusForbidden)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusForbidden),
+			Message: "invalid_oauth_state",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	// пользователь отказался на странице провайдера.
+	if query.Get("error") != "" || query.Get("code") == "" {
+		logging.FromRequest(r).Info().Str("provider", name).Str("error", query.Get("error")).Msg("oauth_access_denied")
+		w.WriteHeader(http.StatusForbidden)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusForbidden),
+			Message: "oauth_access_denied",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	identity, err := provider.Exchange(query.Get("code"), *e.Config.OAuthRedirectURL)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Str("provider", name).Msg("oauth_provider_error")
+		w.WriteHeader(http.StatusBadGateway)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusBadGateway),
+			Message: "oauth_provider_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	if !e.loginWithIdentity(w, r, name, identity) {
+		return
+	}
+	http.Redirect(w, r, *e.Config.OAuthSuccessURL, http.StatusFound)
+}
+
+// Аккаунт провайдера уже связан - вход в связанного пользователя.
+// Иначе, если запрос от обычного пользователя, аккаунт связывается с ним, сессия остаётся прежней.
+// Иначе создаётся новый пользователь. Одноразовый пользователь при этом не меняется.
+// При ошибке сама отправляет ответ, вызывающему остаётся только выйти.
+func (e *Environment) loginWithIdentity(w http.ResponseWriter, r *http.Request, name string, identity oauth.Identity) (ok bool) {
+	exist, userID, err := e.DB.SelectUserIDByExternalLogin(name, identity.Subject)
+	var current accessor.User
+	var loggedIn, isDuplicate bool
+	if err == nil && !exist {
+		loggedIn, current, _, err = e.currentSession(w, r)
+	}
+	switch {
+	case err != nil:
+	case exist:
+		metrics.Logins.WithLabelValues("oauth").Inc()
+	case loggedIn && !current.Disposable:
+		isDuplicate, err = e.DB.InsertIntoExternalLogin(current.Id, name, identity.Subject)
+		if err == nil && !isDuplicate {
+			logging.FromRequest(r).Info().Str("provider", name).Msg("oauth_account_linked")
+			ok = true
+			return
+		}
+	default:
+		userID, err = e.createExternalUser(name, identity)
+		if err == nil {
+			metrics.Registrations.WithLabelValues("oauth").Inc()
+		}
+	}
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	if isDuplicate {
+		w.WriteHeader(http.StatusConflict)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusConflict),
+			Message: "oauth_account_already_linked",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	ok = e.startSession(w, r, userID)
+	return
+}
+
+// Логин берётся у провайдера. Если он занят, к нему добавляется случайный суффикс:
+// пользователь сменит логин потом через PATCH /api/v1/user.
+func (e *Environment) createExternalUser(name string, identity oauth.Identity) (userID accessor.UserID, err error) {
+	base := strings.TrimSpace(identity.Login)
+	if base == "" {
+		base = name + "_player"
+	}
+	login := base
+	for attempt := 0; attempt < externalLoginAttempts; attempt++ {
+		var isDuplicateLogin, linked bool
+		userID, isDuplicateLogin, linked, err = e.DB.InsertExternalUser(login, defaultAvatarURL, name, identity.Subject)
+		if err != nil || !isDuplicateLogin && !linked {
+			return
+		}
+		// тот же аккаунт одновременно вошёл с другого устройства и уже создал пользователя.
+		if linked {
+			_, userID, err = e.DB.SelectUserIDByExternalLogin(name, identity.Subject)
+			return
+		}
+		var suffix string
+		suffix, err = newRandomToken()
+		if err != nil {
+			return
+		}
+		login = base + "_" + suffix[:6]
+	}
+	err = errors.New("no free login for '" + base + "'")
+	return
+}
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/oauth"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
+)
+
+// Провайдер OpenID Connect в памяти. Код вида "<sub>|<логин>" меняется на такого пользователя,
+// код "fail" - ошибка провайдера.
+type fakeProvider struct {
+	url string
+	// обмены кода на токен.
+	exchanges int32
+}
+
+func (f *fakeProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		_, _ = w.Write([]byte(`{
+			"authorization_endpoint": "` + f.url + `/authorize",
+			"token_endpoint": "` + f.url + `/token",
+			"userinfo_endpoint": "` + f.url + `/userinfo"
+		}`))
+	case "/token":
+		atomic.AddInt32(&f.exchanges, 1)
+		code := r.PostFormValue("code")
+		if code == "fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"` + url.QueryEscape(code) + `"}`))
+	case "/userinfo":
+		code, _ := url.QueryUnescape(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		parts := strings.SplitN(code, "|", 2)
+		_, _ = w.Write([]byte(`{"sub":"` + parts[0] + `","preferred_username":"` + parts[1] + `"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// окружение с одним провайдером "test". База подключается отдельно, см. testDatabase.
+func testEnvironment(t *testing.T) (e *Environment, fake *fakeProvider, server *httptest.Server) {
+	fake = &fakeProvider{}
+	server = httptest.NewServer(fake)
+	fake.url = server.URL
+	provider, err := oauth.DiscoverOIDC(server.URL, "id", "secret")
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	redirectURL := "https://example.com/api/v1/oauth/callback"
+	successURL := "/"
+	sessionLifetime := time.Hour
+	clientIPHeader := ""
+	e = &Environment{OAuthProviders: map[string]oauth.Provider{"test": provider}}
+	e.Config.OAuthRedirectURL = &redirectURL
+	e.Config.OAuthSuccessURL = &successURL
+	e.Config.SessionLifetime = &sessionLifetime
+	e.Config.ClientIPHeader = &clientIPHeader
+	return
+}
+
+// начинает вход: возвращает cookie "OAuthState" и state из адреса провайдера.
+func startOAuth(t *testing.T, e *Environment, provider string) (stateCookie *http.Cookie, state string) {
+	recorder := httptest.NewRecorder()
+	e.OAuthLogin(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/oauth/login?provider="+provider, nil))
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("login: %d %s", recorder.Code, recorder.Body.String())
+	}
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state = location.Query().Get("state")
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == "OAuthState" {
+			stateCookie = cookie
+		}
+	}
+	if state == "" || stateCookie == nil {
+		t.Fatalf("no state in %s", location)
+	}
+	return
+}
+
+// возврат от провайдера, cookies - то, что прислал браузер.
+func callback(e *Environment, query url.Values, cookies ...*http.Cookie) (recorder *httptest.ResponseRecorder) {
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/oauth/callback?"+query.Encode(), nil)
+	for _, cookie := range cookies {
+		request.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	recorder = httptest.NewRecorder()
+	e.OAuthCallback(recorder, request)
+	return
+}
+
+func responseMessage(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	response := types.ServerResponse{}
+	if err := response.UnmarshalJSON(recorder.Body.Bytes()); err != nil {
+		t.Fatalf("%d %q: %v", recorder.Code, recorder.Body.String(), err)
+	}
+	return response.Message
+}
+
+func TestOAuthLoginUnknownProvider(t *testing.T) {
+	e, _, server := testEnvironment(t)
+	defer server.Close()
+
+	recorder := httptest.NewRecorder()
+	e.OAuthLogin(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/oauth/login?provider=vk", nil))
+	if recorder.Code != http.StatusNotFound || responseMessage(t, recorder) != "unknown_oauth_provider" {
+		t.Fatalf("%d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+// без верного state код провайдера даже не меняется на токен.
+func TestOAuthCallbackInvalidState(t *testing.T) {
+	e, fake, server := testEnvironment(t)
+	defer server.Close()
+	stateCookie, state := startOAuth(t, e, "test")
+
+	cases := []struct {
+		name    string
+		state   string
+		cookies []*http.Cookie
+	}{
+		{"no cookie", state, nil},
+		{"other state", "other", []*http.Cookie{stateCookie}},
+		{"empty state", "", []*http.Cookie{{Name: "OAuthState", Value: "test:"}}},
+		{"unknown provider", state, []*http.Cookie{{Name: "OAuthState", Value: "vk:" + state}}},
+		{"no provider", state, []*http.Cookie{{Name: "OAuthState", Value: state}}},
+	}
+	for _, c := range cases {
+		recorder := callback(e, url.Values{"code": {"s|login"}, "state": {c.state}}, c.cookies...)
+		if recorder.Code != http.StatusForbidden || responseMessage(t, recorder) != "invalid_oauth_state" {
+			t.Fatalf("%s: %d %s", c.name, recorder.Code, recorder.Body.String())
+		}
+	}
+	if exchanges := atomic.LoadInt32(&fake.exchanges); exchanges != 0 {
+		t.Fatalf("%d codes exchanged with invalid state", exchanges)
+	}
+}
+
+func TestOAuthCallbackAccessDenied(t *testing.T) {
+	e, fake, server := testEnvironment(t)
+	defer server.Close()
+	stateCookie, state := startOAuth(t, e, "test")
+
+	recorder := callback(e, url.Values{"error": {"access_denied"}, "state": {state}}, stateCookie)
+	if recorder.Code != http.StatusForbidden || responseMessage(t, recorder) != "oauth_access_denied" {
+		t.Fatalf("%d %s", recorder.Code, recorder.Body.String())
+	}
+	if atomic.LoadInt32(&fake.exchanges) != 0 {
+		t.Fatal("code exchanged after access denied")
+	}
+}
+
+func TestOAuthCallbackProviderError(t *testing.T) {
+	e, _, server := testEnvironment(t)
+	defer server.Close()
+	stateCookie, state := startOAuth(t, e, "test")
+
+	recorder := callback(e, url.Values{"code": {"fail"}, "state": {state}}, stateCookie)
+	if recorder.Code != http.StatusBadGateway || responseMessage(t, recorder) != "oauth_provider_error" {
+		t.Fatalf("%d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+// Дальше тесты с базой: строка подключения к пустой базе в $TEST_POSTGRES_PATH,
+// без неё тесты пропускаются. Схема накатывается миграциями.
+var testDatabase struct {
+	once sync.Once
+	db   accessor.DB
+	err  error
+}
+
+func connectTestDatabase(t *testing.T, e *Environment) {
+	path := os.Getenv("TEST_POSTGRES_PATH")
+	if path == "" {
+		t.Skip("$TEST_POSTGRES_PATH is not set")
+	}
+	// prepared statements общие для пакета accessor, готовятся один раз.
+	testDatabase.once.Do(func() {
+		testDatabase.db, testDatabase.err = accessor.ConnectToDatabase(path)
+		if testDatabase.err == nil {
+			testDatabase.err = testDatabase.db.MigrateTo(accessor.LatestSchemaVersion())
+		}
+		if testDatabase.err == nil {
+			testDatabase.err = testDatabase.db.InitDatabase()
+		}
+	})
+	if testDatabase.err != nil {
+		t.Fatal(testDatabase.err)
+	}
+	e.DB = testDatabase.db
+	return
+}
+
+// уникальная строка, что бы тесты не мешали друг другу и прошлым запускам.
+func uniqueName(t *testing.T, prefix string) string {
+	token, err := newRandomToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prefix + "_" + token[:8]
+}
+
+// обычный пользователь с сессией, token - значение cookie SessionId.
+func testUser(t *testing.T, e *Environment, login string) (userID accessor.UserID, token string) {
+	userID, isDuplicate, err := e.DB.InsertIntoUser(login, defaultAvatarURL, false)
+	if err != nil || isDuplicate {
+		t.Fatalf("insert user %q: %v, duplicate %v", login, err, isDuplicate)
+	}
+	token, err = newRandomToken()
+	if err == nil {
+		err = e.DB.InsertIntoSession(userID, hashSessionToken(token), "test", "csrf", time.Hour)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+// пользователь, в которого вошли: по cookie SessionId из ответа.
+func loggedInUser(t *testing.T, e *Environment, recorder *httptest.ResponseRecorder) (user accessor.User) {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name != "SessionId" {
+			continue
+		}
+		exist, user, _, err := e.DB.SelectUserBySessionTokenHash(hashSessionToken(cookie.Value))
+		if err != nil || !exist {
+			t.Fatalf("session of the response: %v, exist %v", err, exist)
+		}
+		return user
+	}
+	t.Fatalf("no session cookie in %d %s", recorder.Code, recorder.Body.String())
+	return
+}
+
+func deleteUsers(e *Environment, users ...accessor.UserID) {
+	for _, userID := range users {
+		_, _, _ = e.DB.DeleteUser(userID)
+	}
+}
+
+// первый вход создаёт пользователя, второй входит в него же.
+func TestOAuthCreatesUser(t *testing.T) {
+	e, _, server := testEnvironment(t)
+	defer server.Close()
+	connectTestDatabase(t, e)
+	subject, login := uniqueName(t, "sub"), uniqueName(t, "player")
+
+	var users []accessor.UserID
+	for i := 0; i < 2; i++ {
+		stateCookie, state := startOAuth(t, e, "test")
+		recorder := callback(e, url.Values{"code": {subject + "|" + login}, "state": {state}}, stateCookie)
+		if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/" {
+			t.Fatalf("%d %s", recorder.Code, recorder.Body.String())
+		}
+		user := loggedInUser(t, e, recorder)
+		if user.Login != login || user.Disposable {
+			t.Fatalf("logged in as %+v", user)
+		}
+		users = append(users, user.Id)
+	}
+	defer deleteUsers(e, users...)
+	if users[0] != users[1] {
+		t.Fatalf("second login created user %d, first %d", users[1], users[0])
+	}
+}
+
+// занятый логин получает случайный суффикс.
+func TestOAuthLoginCollision(t *testing.T) {
+	e, _, server := testEnvironment(t)
+	defer server.Close()
+	connectTestDatabase(t, e)
+	login := uniqueName(t, "player")
+	existing, _ := testUser(t, e, login)
+	defer deleteUsers(e, existing)
+
+	stateCookie, state := startOAuth(t, e, "test")
+	recorder := callback(e, url.Values{"code": {uniqueName(t, "sub") + "|" + login}, "state": {state}}, stateCookie)
+	user := loggedInUser(t, e, recorder)
+	defer deleteUsers(e, user.Id)
+	if user.Id == existing || !strings.HasPrefix(user.Login, login+"_") || len(user.Login) != len(login)+7 {
+		t.Fatalf("new user %+v, existing %d with login %q", user, existing, login)
+	}
+}
+
+// вошедший обычный пользователь получает связь с аккаунтом провайдера, сессия прежняя.
+func TestOAuthLinkToCurrentUser(t *testing.T) {
+	e, _, server := testEnvironment(t)
+	defer server.Close()
+	connectTestDatabase(t, e)
+	userID, token := testUser(t, e, uniqueName(t, "player"))
+	defer deleteUsers(e, userID)
+	subject := uniqueName(t, "sub")
+
+	stateCookie, state := startOAuth(t, e, "test")
+	recorder := callback(e, url.Values{"code": {subject + "|other"}, "state": {state}},
+		stateCookie, &http.Cookie{Name: "SessionId", Value: token})
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("%d %s", recorder.Code, recorder.Body.String())
+	}
+	exist, linked, err := e.DB.SelectUserIDByExternalLogin("test", subject)
+	if err != nil || !exist || linked != userID {
+		t.Fatalf("provider account linked to %d (%v, %v), want %d", linked, exist, err, userID)
+	}
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == "SessionId" {
+			t.Fatal("linking started a new session")
+		}
+	}
+}
+
+// второй аккаунт того же провайдера к пользователю не привязывается.
+func TestOAuthAlreadyLinked(t *testing.T) {
+	e, _, server := testEnvironment(t)
+	defer server.Close()
+	connectTestDatabase(t, e)
+	userID, token := testUser(t, e, uniqueName(t, "player"))
+	defer deleteUsers(e, userID)
+	isDuplicate, err := e.DB.InsertIntoExternalLogin(userID, "test", uniqueName(t, "sub"))
+	if err != nil || isDuplicate {
+		t.Fatalf("link: %v, duplicate %v", err, isDuplicate)
+	}
+
+	subject := uniqueName(t, "sub")
+	stateCookie, state := startOAuth(t, e, "test")
+	recorder := callback(e, url.Values{"code": {subject + "|other"}, "state": {state}},
+		stateCookie, &http.Cookie{Name: "SessionId", Value: token})
+	if recorder.Code != http.StatusConflict || responseMessage(t, recorder) != "oauth_account_already_linked" {
+		t.Fatalf("%d %s", recorder.Code, recorder.Body.String())
+	}
+	if exist, _, _ := e.DB.SelectUserIDByExternalLogin("test", subject); exist {
+		t.Fatal("second provider account is linked")
+	}
+}
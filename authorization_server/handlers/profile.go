@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/password"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
+)
+
+// UpdateProfile godoc
+// @Summary Change login or password.
+// @Description Change login of the current user, the old one is kept in the rename history. Changing password requires the current one and ends all other sessions.
+// @Tags user
+// @Accept application/json
+// @Produce application/json
+// @Param update body types.ProfileUpdate true "login, currentPassword, newPassword"
+// @Success 200 {object} types.ServerResponse
+// @Failure 400 {object} types.ServerResponse
+// @Failure 403 {object} types.ServerResponse
+// @Failure 409 {object} types.ServerResponse
+// @Failure 422 {object} types.ServerResponse
+// @Failure 429 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/user [patch]
+func (e *Environment) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	_ = r.Body.Close()
+
+	user, session, ok := e.authorizedSession(w, r)
+	if !ok {
+		return
+	}
+
+	update := types.ProfileUpdate{}
+	err = update.UnmarshalJSON(bodyBytes)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusBadRequest),
+			Message: "invalid_request_format",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	if update.Login == "" && update.NewPassword == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusUnprocessableEntity),
+			Message: "nothing_to_change",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	// пароль проверяется до изменений, что бы неудачный запрос ничего не менял.
+	var passwordHash string
+	if update.NewPassword != "" {
+		passwordHash, ok = e.newPasswordHash(w, r, user, update)
+		if !ok {
+			return
+		}
+	}
+
+	// логин, пароль и сессии меняются одним запросом: если логин занят, пароль тоже не меняется.
+	var login string
+	if update.Login != user.Login {
+		login = update.Login
+	}
+	exist, isDuplicate, err := e.DB.UpdateProfile(user.Id, login, passwordHash, session.Id)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	if isDuplicate {
+		w.WriteHeader(http.StatusConflict)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusConflict),
+			Message: "login_is_not_unique",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	// параллельный запрос удалил аккаунт.
+	if !exist {
+		w.WriteHeader(http.StatusForbidden)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusForbidden),
+			Message: "unauthorized_user",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	if login != "" {
+		logging.FromRequest(r).Info().Str("old_login", user.Login).Str("new_login", login).Msg("login_changed")
+		logging.SetLogin(r, login)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "successful_profile_update",
+	}.MarshalJSON()
+	_, _ = w.Write(response)
+}
+
+// проверяет текущий пароль и возвращает хеш нового.
+// Подбор текущего пароля ограничивается так же, как подбор при входе.
+// Ограничение проверяется первым, до запросов к базе. Ключ - id пользователя,
+// а не логин: логин можно сменить этим же запросом.
+// При ошибке сама отправляет ответ, вызывающему остаётся только выйти.
+func (e *Environment) newPasswordHash(w http.ResponseWriter, r *http.Request, user accessor.User, update types.ProfileUpdate) (passwordHash string, ok bool) {
+	account := userAccount(user.Id)
+	if !e.allowAttempt(w, r, account) {
+		return
+	}
+
+	if len(update.NewPassword) < 5 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusUnprocessableEntity),
+			Message: "weak_password",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	exist, currentHash, err := e.DB.SelectPasswordHashByUserID(user.Id)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	// одноразовый пользователь задаёт первый пароль через /api/v1/user/upgrade,
+	// а созданный входом через провайдера входит только через него.
+	if !exist {
+		message := "password_is_not_set"
+		if user.Disposable {
+			message = "user_is_disposable"
+		}
+		w.WriteHeader(http.StatusConflict)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusConflict),
+			Message: message,
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	match, _, err := password.Verify(update.CurrentPassword, currentHash)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Int32("user_id", int32(user.Id)).Msg("invalid_password_hash")
+	}
+	if !match {
+		e.attemptFailed(r, account)
+		w.WriteHeader(http.StatusForbidden)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusForbidden),
+			Message: "wrong_password",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	e.attemptSucceeded(r, account)
+
+	passwordHash, err = password.Hash(update.NewPassword)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("password_hashing_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "password_hashing_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	ok = true
+	return
+}
+
+// DeleteAccount godoc
+// @Summary Delete account.
+// @Description Delete the current user with statistics, sessions, formations and avatar. The login becomes free.
+// @Tags user
+// @Accept application/json
+// @Produce application/json
+// @Success 200 {object} types.ServerResponse
+// @Failure 403 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/user [delete]
+func (e *Environment) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = r.Body.Close()
+
+	user, ok := e.authorizedUser(w, r)
+	if !ok {
+		return
+	}
+
+	// статистика, сессии и расстановки удаляются через "on delete cascade".
+	exist, avatarAddress, err := e.DB.DeleteUser(user.Id)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	// параллельный запрос уже удалил аккаунт.
+	if !exist {
+		w.WriteHeader(http.StatusForbidden)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusForbidden),
+			Message: "unauthorized_user",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	e.removeAvatar(r, avatarAddress)
+	clearSessionCookies(w)
+	logging.FromRequest(r).Info().Int32("user_id", int32(user.Id)).Msg("account_deleted")
+
+	w.WriteHeader(http.StatusOK)
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "successful_account_deletion",
+	}.MarshalJSON()
+	_, _ = w.Write(response)
+	metrics.AccountsDeleted.Inc()
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
+)
+
+// сессия продлевается не чаще этого интервала, что бы не писать в базу на каждый запрос.
+const sessionRenewalInterval = time.Minute
+
+// 256 бит из crypto/rand, подобрать или предсказать такой токен нельзя.
+// Используется и для cookie сессии, и для токена CSRF.
+func newRandomToken() (token string, err error) {
+	buffer := make([]byte, 32)
+	_, err = rand.Read(buffer)
+	if err != nil {
+		return
+	}
+	token = base64.RawURLEncoding.EncodeToString(buffer)
+	return
+}
+
+// в базе хранится только хеш токена. Токен и так случаен, соль и медленный хеш не нужны.
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Lax: браузер не посылает cookie в POST с чужих сайтов, но переход по ссылке
+// на сайт остаётся авторизованным.
+func (e *Environment) setSessionCookies(w http.ResponseWriter, token string, csrfToken string) {
+	expires := time.Now().Add(*e.Config.SessionLifetime)
+	http.SetCookie(w, &http.Cookie{
+		Name:     "SessionId",
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	// не HttpOnly: клиент читает его и повторяет в заголовке X-CSRF-Token.
+	// Чужой сайт прочитать cookie не может, поэтому и повторить токен тоже.
+	http.SetCookie(w, &http.Cookie{
+		Name:     "CSRFToken",
+		Value:    csrfToken,
+		Path:     "/",
+		Expires:  expires,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// стирает обе cookie сессии у клиента.
+func clearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{"SessionId", "CSRFToken"} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Path:     "/",
+			Expires:  time.Unix(0, 0),
+			Secure:   true,
+			HttpOnly: name == "SessionId",
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// запросы, которые ничего не меняют, токен CSRF не проверяют.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// токен из заголовка X-CSRF-Token или, для html форм, из поля csrf_token
+// должен совпасть с токеном сессии.
+func validCSRFToken(r *http.Request, session accessor.Session) bool {
+	token := r.Header.Get("X-CSRF-Token")
+	if token == "" {
+		token = r.FormValue("csrf_token")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) == 1
+}
+
+// создаёт новую сессию пользователя для устройства, с которого пришёл запрос, и ставит cookie.
+// Прочие сессии пользователя остаются, удаляются только истёкшие.
+// При ошибке сама отправляет ответ, вызывающему остаётся только выйти.
+func (e *Environment) startSession(w http.ResponseWriter, r *http.Request, userID accessor.UserID) (ok bool) {
+	token, err := newRandomToken()
+	var csrfToken string
+	if err == nil {
+		csrfToken, err = newRandomToken()
+	}
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("session_token_generation_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "session_token_generation_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	err = e.DB.DeleteExpiredSessions(userID)
+	if err == nil {
+		err = e.DB.InsertIntoSession(userID, hashSessionToken(token), r.UserAgent(), csrfToken, *e.Config.SessionLifetime)
+	}
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	e.setSessionCookies(w, token, csrfToken)
+	ok = true
+	return
+}
+
+// находит живую сессию по cookie SessionId и продлевает её.
+// exist == false, если cookie нет, сессия отозвана или истекла.
+func (e *Environment) currentSession(w http.ResponseWriter, r *http.Request) (exist bool, user accessor.User, session accessor.Session, err error) {
+	cookie, err := r.Cookie("SessionId")
+	if err != nil || cookie.Value == "" {
+		err = nil
+		return
+	}
+	exist, user, session, err = e.DB.SelectUserBySessionTokenHash(hashSessionToken(cookie.Value))
+	if err != nil || !exist {
+		return
+	}
+	if time.Since(session.LastSeenAt) > sessionRenewalInterval {
+		// не продлённая сессия ещё действует, поэтому ошибка только записывается в лог.
+		renewalErr := e.DB.RenewSession(session.Id, *e.Config.SessionLifetime)
+		if renewalErr != nil {
+			logging.FromRequest(r).Warn().Err(renewalErr).Msg("session_renewal_error")
+		} else {
+			e.setSessionCookies(w, cookie.Value, session.CSRFToken)
+		}
+	}
+	return
+}
+
+// Sessions godoc
+// @Summary List sessions.
+// @Description Return active sessions of the current user on all devices, the one the request came from is marked as current.
+// @Tags session
+// @Accept application/json
+// @Produce application/json
+// @Success 200 {array} types.Session
+// @Failure 403 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/sessions [get]
+func (e *Environment) Sessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = r.Body.Close()
+
+	exist, user, current, err := e.currentSession(w, r)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	if !exist {
+		w.WriteHeader(http.StatusForbidden)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusForbidden),
+			Message: "unauthorized_user",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	logging.SetLogin(r, user.Login)
+
+	sessions, err := e.DB.SelectSessionsByUserID(user.Id)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	result := types.Sessions{}
+	for _, session := range sessions {
+		result = append(result, types.Session{
+			Id:         int32(session.Id),
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.Id == current.Id,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response, _ := result.MarshalJSON()
+	_, _ = w.Write(response)
+}
+
+// RevokeSession godoc
+// @Summary Revoke session.
+// @Description Log out one of the devices of the current user, session id is taken from GET /api/v1/sessions.
+// @Tags session
+// @Accept application/json
+// @Produce application/json
+// @Param id query int true "session id"
+// @Success 200 {object} types.ServerResponse
+// @Failure 400 {object} types.ServerResponse
+// @Failure 403 {object} types.ServerResponse
+// @Failure 404 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/sessions [delete]
+func (e *Environment) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = r.Body.Close()
+
+	user, ok := e.authorizedUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusBadRequest),
+			Message: "invalid_session_id",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	exist, err := e.DB.DeleteSessionByIDAndUserID(accessor.SessionID(sessionID), user.Id)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	if !exist {
+		w.WriteHeader(http.StatusNotFound)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusNotFound),
+			Message: "target_session_not_found",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "successful_session_revocation",
+	}.MarshalJSON()
+	_, _ = w.Write(response)
+}
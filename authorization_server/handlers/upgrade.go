@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/password"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/types"
+)
+
+// UpgradeDisposableUser godoc
+// @Summary Turn temporary user into regular one.
+// @Description Set password for the current temporary user. Login, sessions and formations stay, the user appears in the leaderboard and is no longer deleted.
+// @Tags user
+// @Accept application/json
+// @Produce application/json
+// @Param upgrade body types.DisposableUserUpgrade true "password"
+// @Success 200 {object} types.ServerResponse
+// @Failure 400 {object} types.ServerResponse
+// @Failure 403 {object} types.ServerResponse
+// @Failure 409 {object} types.ServerResponse
+// @Failure 422 {object} types.ServerResponse
+// @Failure 500 {object} types.ServerResponse
+// @Router /api/v1/user/upgrade [post]
+func (e *Environment) UpgradeDisposableUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	_ = r.Body.Close()
+
+	user, ok := e.authorizedUser(w, r)
+	if !ok {
+		return
+	}
+
+	upgrade := types.DisposableUserUpgrade{}
+	err = upgrade.UnmarshalJSON(bodyBytes)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusBadRequest),
+			Message: "invalid_request_format",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	if len(upgrade.Password) < 5 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusUnprocessableEntity),
+			Message: "weak_password",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	passwordHash, err := password.Hash(upgrade.Password)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("password_hashing_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "password_hashing_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	// проверка disposable в том же запросе, что и обновление: двойной запрос не создаст второй пароль.
+	upgraded, err := e.DB.UpgradeDisposableUser(user.Id, passwordHash)
+	if err != nil {
+		logging.FromRequest(r).Error().Err(err).Msg("database_error")
+		w.WriteHeader(http.StatusInternalServerError)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusInternalServerError),
+			Message: "database_error",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+	if !upgraded {
+		w.WriteHeader(http.StatusConflict)
+		response, _ := types.ServerResponse{
+			Status:  http.StatusText(http.StatusConflict),
+			Message: "user_is_not_disposable",
+		}.MarshalJSON()
+		_, _ = w.Write(response)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "successful_account_upgrade",
+	}.MarshalJSON()
+	_, _ = w.Write(response)
+	metrics.DisposableUsersUpgraded.Inc()
+}
@@ -0,0 +1,54 @@
+// Фоновая уборка одноразовых пользователей.
+// Они создаются на одну партию и сами не выходят, если закрыть вкладку, поэтому
+// без уборки их логины оставались бы занятыми навсегда.
+
+package janitor
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+)
+
+type Janitor struct {
+	DB accessor.DB
+	// одноразовый пользователь удаляется, если от него не было запросов дольше TTL.
+	TTL time.Duration
+	// как часто искать таких пользователей.
+	Interval time.Duration
+}
+
+func NewJanitor(db accessor.DB, ttl time.Duration, interval time.Duration) (j *Janitor) {
+	j = &Janitor{
+		DB:       db,
+		TTL:      ttl,
+		Interval: interval,
+	}
+	return
+}
+
+// не возвращает управление, запускается в отдельной горутине.
+func (j *Janitor) Run() {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		j.sweep()
+	}
+}
+
+// ошибка только записывается в лог: следующий проход удалит пропущенных.
+func (j *Janitor) sweep() {
+	deleted, err := j.DB.DeleteInactiveDisposableUsers(j.TTL)
+	if err != nil {
+		log.Error().Err(err).Msg("disposable_users_cleanup_error")
+		return
+	}
+	if deleted != 0 {
+		metrics.DisposableUsersDeleted.WithLabelValues("inactivity").Add(float64(deleted))
+		log.Info().Int64("deleted", deleted).Msg("disposable_users_deleted")
+	}
+	return
+}
@@ -0,0 +1,161 @@
+// Ограничение частоты попыток входа и регистрации.
+// Ключ - строка вида "ip:1.2.3.4", "login:JohanDoe" или "user:42", у каждого ключа своя история.
+
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// Хранилище истории попыток. Сейчас есть только Memory, но обработчики зависят
+// от интерфейса, что бы несколько экземпляров сервера могли делить историю в Postgres.
+type Limiter interface {
+	// можно ли сделать попытку сейчас. Разрешённая попытка расходует частоту ключа.
+	// Если нельзя - retryAfter, через сколько можно будет.
+	Allow(key string) (allowed bool, retryAfter time.Duration, err error)
+	// неудачная попытка (неверный пароль): следующая разрешается только после паузы,
+	// которая удваивается с каждой неудачей подряд.
+	Failure(key string) (err error)
+	// удачная попытка забывает неудачи.
+	Reset(key string) (err error)
+}
+
+type Config struct {
+	// разрешённых попыток в минуту, 0 - без ограничения.
+	PerMinute int
+	// пауза после первой неудачи, дальше удваивается до MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// после стольких неудач подряд ключ блокируется на LockoutDuration, 0 - без блокировки.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// неудачи старше этого срока забываются.
+	Window time.Duration
+}
+
+type entry struct {
+	// token bucket: попытки копятся со скоростью PerMinute, но не больше PerMinute.
+	tokens     float64
+	lastRefill time.Time
+	failures   int
+	lastFail   time.Time
+	blocked    time.Time // до этого момента попытки запрещены
+}
+
+// Limiter в памяти процесса: история теряется при перезапуске и не общая для экземпляров.
+type Memory struct {
+	Config Config
+	// текущее время, тесты подменяют его.
+	Now       func() time.Time
+	mutex     sync.Mutex
+	entries   map[string]*entry
+	lastPrune time.Time
+}
+
+func NewMemory(config Config) (m *Memory) {
+	m = &Memory{
+		Config:    config,
+		Now:       time.Now,
+		entries:   make(map[string]*entry),
+		lastPrune: time.Now(),
+	}
+	return
+}
+
+func (m *Memory) Allow(key string) (allowed bool, retryAfter time.Duration, err error) {
+	now := m.Now()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.prune(now)
+	e := m.entry(key, now)
+	if now.Before(e.blocked) {
+		retryAfter = e.blocked.Sub(now)
+		return
+	}
+	if m.Config.PerMinute > 0 {
+		e.tokens += now.Sub(e.lastRefill).Minutes() * float64(m.Config.PerMinute)
+		if e.tokens > float64(m.Config.PerMinute) {
+			e.tokens = float64(m.Config.PerMinute)
+		}
+		e.lastRefill = now
+		if e.tokens < 1 {
+			retryAfter = time.Duration((1 - e.tokens) / float64(m.Config.PerMinute) * float64(time.Minute))
+			return
+		}
+		e.tokens--
+	}
+	allowed = true
+	return
+}
+
+func (m *Memory) Failure(key string) (err error) {
+	now := m.Now()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	e := m.entry(key, now)
+	if now.Sub(e.lastFail) > m.Config.Window {
+		e.failures = 0
+	}
+	e.failures++
+	e.lastFail = now
+	var blocked time.Time
+	if m.Config.LockoutThreshold > 0 && e.failures >= m.Config.LockoutThreshold {
+		blocked = now.Add(m.Config.LockoutDuration)
+		e.failures = 0
+	} else {
+		backoff := m.Config.Backoff
+		for i := 1; i < e.failures && backoff < m.Config.MaxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > m.Config.MaxBackoff {
+			backoff = m.Config.MaxBackoff
+		}
+		blocked = now.Add(backoff)
+	}
+	// параллельные запросы проходят Allow до блокировки, их неудачи приходят после:
+	// короткая пауза не должна заменить блокировку.
+	if blocked.After(e.blocked) {
+		e.blocked = blocked
+	}
+	return
+}
+
+func (m *Memory) Reset(key string) (err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if e, ok := m.entries[key]; ok {
+		e.failures = 0
+		e.blocked = time.Time{}
+	}
+	return
+}
+
+// вызывается под mutex.
+func (m *Memory) entry(key string, now time.Time) (e *entry) {
+	e, ok := m.entries[key]
+	if !ok {
+		e = &entry{
+			tokens:     float64(m.Config.PerMinute),
+			lastRefill: now,
+		}
+		m.entries[key] = e
+	}
+	return
+}
+
+// раз в минуту удаляет ключи, про которые нечего помнить: частота восстановилась,
+// блокировка прошла, неудачи забыты. Иначе перебор логинов раздувал бы map.
+// Вызывается под mutex.
+func (m *Memory) prune(now time.Time) {
+	if now.Sub(m.lastPrune) < time.Minute {
+		return
+	}
+	m.lastPrune = now
+	for key, e := range m.entries {
+		if now.Sub(e.lastRefill) >= time.Minute && now.After(e.blocked) && now.Sub(e.lastFail) > m.Config.Window {
+			delete(m.entries, key)
+		}
+	}
+	return
+}
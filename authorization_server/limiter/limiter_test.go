@@ -0,0 +1,200 @@
+package limiter
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// часы, которые идут только по advance.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestMemory(config Config) (m *Memory, clock *fakeClock) {
+	m = NewMemory(config)
+	clock = &fakeClock{now: m.lastPrune}
+	m.Now = clock.Now
+	return
+}
+
+func mustAllow(t *testing.T, m *Memory, key string) {
+	allowed, retryAfter, err := m.Allow(key)
+	if err != nil || !allowed {
+		t.Fatalf("%s: not allowed, retry after %v, %v", key, retryAfter, err)
+	}
+}
+
+func mustDeny(t *testing.T, m *Memory, key string, wantRetryAfter time.Duration) {
+	allowed, retryAfter, err := m.Allow(key)
+	if err != nil || allowed {
+		t.Fatalf("%s: allowed, %v", key, err)
+	}
+	// retryAfter считается через float64 минут, точность - до микросекунды.
+	if diff := retryAfter - wantRetryAfter; diff < -time.Microsecond || diff > time.Microsecond {
+		t.Fatalf("%s: retry after %v, want %v", key, retryAfter, wantRetryAfter)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	// одна попытка в 10 секунд.
+	m, clock := newTestMemory(Config{PerMinute: 6, Window: time.Hour})
+
+	for i := 0; i < 6; i++ {
+		mustAllow(t, m, "ip:1")
+	}
+	mustDeny(t, m, "ip:1", 10*time.Second)
+	// у другого ключа своя частота.
+	mustAllow(t, m, "ip:2")
+
+	clock.advance(4 * time.Second)
+	mustDeny(t, m, "ip:1", 6*time.Second)
+	clock.advance(6 * time.Second)
+	mustAllow(t, m, "ip:1")
+	mustDeny(t, m, "ip:1", 10*time.Second)
+
+	// за долгий простой копится не больше PerMinute попыток.
+	clock.advance(time.Hour)
+	for i := 0; i < 6; i++ {
+		mustAllow(t, m, "ip:1")
+	}
+	mustDeny(t, m, "ip:1", 10*time.Second)
+}
+
+func TestFailureBackoff(t *testing.T) {
+	m, clock := newTestMemory(Config{Backoff: time.Second, MaxBackoff: 10 * time.Second, Window: time.Hour})
+
+	for i, want := range []time.Duration{1, 2, 4, 8, 10, 10} {
+		if err := m.Failure("login:a"); err != nil {
+			t.Fatal(err)
+		}
+		mustDeny(t, m, "login:a", want*time.Second)
+		clock.advance(want*time.Second - time.Millisecond)
+		mustDeny(t, m, "login:a", time.Millisecond)
+		clock.advance(time.Millisecond)
+		if allowed, _, _ := m.Allow("login:a"); !allowed {
+			t.Fatalf("failure %d: not allowed after backoff", i+1)
+		}
+	}
+
+	// удачный вход забывает неудачи.
+	if err := m.Reset("login:a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = m.Failure("login:a")
+	mustDeny(t, m, "login:a", time.Second)
+}
+
+// неудачи старше Window не учитываются в паузе.
+func TestFailuresForgottenAfterWindow(t *testing.T) {
+	m, clock := newTestMemory(Config{Backoff: time.Second, MaxBackoff: time.Minute, Window: 10 * time.Minute})
+
+	_ = m.Failure("login:a")
+	_ = m.Failure("login:a")
+	mustDeny(t, m, "login:a", 2*time.Second)
+	clock.advance(11 * time.Minute)
+	_ = m.Failure("login:a")
+	mustDeny(t, m, "login:a", time.Second)
+}
+
+func TestLockout(t *testing.T) {
+	m, clock := newTestMemory(Config{
+		Backoff:          time.Second,
+		MaxBackoff:       time.Minute,
+		LockoutThreshold: 3,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	})
+
+	for i := 0; i < 2; i++ {
+		_ = m.Failure("login:a")
+		clock.advance(time.Minute)
+		mustAllow(t, m, "login:a")
+	}
+	_ = m.Failure("login:a")
+	mustDeny(t, m, "login:a", 15*time.Minute)
+	clock.advance(14 * time.Minute)
+	mustDeny(t, m, "login:a", time.Minute)
+	clock.advance(time.Minute)
+	mustAllow(t, m, "login:a")
+
+	// после блокировки неудачи считаются заново: следующая даёт обычную паузу.
+	_ = m.Failure("login:a")
+	mustDeny(t, m, "login:a", time.Second)
+	clock.advance(time.Second)
+
+	_ = m.Failure("login:a")
+	_ = m.Failure("login:a")
+	mustDeny(t, m, "login:a", 15*time.Minute)
+	// неудача параллельного запроса, прошедшего Allow до блокировки, её не сокращает.
+	_ = m.Failure("login:a")
+	mustDeny(t, m, "login:a", 15*time.Minute)
+
+	// Reset снимает и блокировку.
+	if err := m.Reset("login:a"); err != nil {
+		t.Fatal(err)
+	}
+	mustAllow(t, m, "login:a")
+}
+
+func keys(m *Memory) string {
+	list := make([]string, 0, len(m.entries))
+	for key := range m.entries {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+func TestPrune(t *testing.T) {
+	m, clock := newTestMemory(Config{
+		PerMinute:        10,
+		Backoff:          time.Second,
+		MaxBackoff:       time.Minute,
+		LockoutThreshold: 2,
+		LockoutDuration:  2 * time.Hour,
+		Window:           time.Hour,
+	})
+
+	mustAllow(t, m, "ip:idle")
+	_ = m.Failure("login:failed")
+	_ = m.Failure("login:locked")
+	_ = m.Failure("login:locked")
+
+	// чистка не чаще раза в минуту.
+	clock.advance(30 * time.Second)
+	mustAllow(t, m, "ip:new")
+	if got := keys(m); got != "ip:idle,ip:new,login:failed,login:locked" {
+		t.Fatalf("pruned before a minute passed: %s", got)
+	}
+
+	// частота восстановилась, но неудачи и блокировка ещё помнятся.
+	clock.advance(time.Minute)
+	mustAllow(t, m, "ip:other")
+	if got := keys(m); got != "ip:other,login:failed,login:locked" {
+		t.Fatalf("after a minute: %s", got)
+	}
+
+	// неудачи забыты, блокировка ещё действует.
+	clock.advance(time.Hour)
+	mustAllow(t, m, "ip:other")
+	if got := keys(m); got != "ip:other,login:locked" {
+		t.Fatalf("after the window: %s", got)
+	}
+	mustDeny(t, m, "login:locked", 2*time.Hour-time.Hour-90*time.Second)
+
+	clock.advance(time.Hour)
+	mustAllow(t, m, "ip:last")
+	if got := keys(m); got != "ip:last" {
+		t.Fatalf("after the lockout: %s", got)
+	}
+}
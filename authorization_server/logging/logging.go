@@ -0,0 +1,99 @@
+// Настройка единого структурированного логгера zerolog.
+// Все пакеты пишут через github.com/rs/zerolog/log, добавляя поля:
+// "request_id", "login", "session" (только через Session).
+
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// настраивает глобальный логгер: level ∈ ["debug", "info", "warn", "error"],
+// format ∈ ["json", "console"], console - для чтения человеком при разработке.
+func Setup(level string, format string) (err error) {
+	logLevel, err := zerolog.ParseLevel(level)
+	if err != nil {
+		err = errors.Wrap(err, "unknown log level '"+level+"': ")
+		return
+	}
+	zerolog.SetGlobalLevel(logLevel)
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	switch format {
+	case "json":
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+	case "console":
+		log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
+	default:
+		err = errors.New("unknown log format '" + format + "', available only ['json', 'console']")
+	}
+	return
+}
+
+// токен сессии даёт доступ к аккаунту, поэтому в лог пишется только начало его sha256:
+// этого достаточно, что бы связать записи одной сессии.
+func Session(token string) (redacted string) {
+	if token == "" {
+		return
+	}
+	hash := sha256.Sum256([]byte(token))
+	redacted = hex.EncodeToString(hash[:4])
+	return
+}
+
+// запоминает код ответа для записи в лог.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Оборачивает все обработчики: присваивает запросу request_id (из заголовка X-Request-Id,
+// который ставит Nginx, или случайный), кладёт логгер с ним в контекст запроса
+// и пишет одну запись на каждый запрос. Обработчики получают логгер через FromRequest.
+func Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get("X-Request-Id")
+		if requestID == "" {
+			requestID = strconv.FormatUint(rand.Uint64(), 36)
+		}
+		w.Header().Set("X-Request-Id", requestID)
+		logger := log.With().Str("request_id", requestID).Logger()
+		r = r.WithContext(logger.WithContext(r.Context()))
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		zerolog.Ctx(r.Context()).Info().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", recorder.status).
+			Dur("duration", time.Since(start)).
+			Msg("request")
+	})
+}
+
+// логгер запроса с полем "request_id", после авторизации - и с "login".
+func FromRequest(r *http.Request) *zerolog.Logger {
+	return zerolog.Ctx(r.Context())
+}
+
+// добавляет к логгеру запроса поле "login" авторизованного пользователя.
+func SetLogin(r *http.Request, login string) {
+	FromRequest(r).UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str("login", login)
+	})
+	return
+}
@@ -1,19 +1,28 @@
 package main
 
 import (
-	"fmt"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
 	flag "github.com/spf13/pflag" // ради gnu style: --flag='value'
-	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/avatar"
 	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/environment"
 	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/handlers"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/janitor"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/limiter"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/metrics"
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/oauth"
 )
 
 func registerUsersHandlers(handlersEnv handlers.Environment) {
-	http.Handle("/api/v1/users", http.HandlerFunc(
+	http.Handle("/api/v1/users", metrics.Instrument("users", http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			// setupResponse(w, r)
 			switch r.Method {
@@ -22,11 +31,11 @@ func registerUsersHandlers(handlersEnv handlers.Environment) {
 			default:
 				handlersEnv.ErrorMethodNotAllowed(w, r)
 			}
-		}))
+		})))
 }
 
 func registerSessionHandlers(handlersEnv handlers.Environment) {
-	http.Handle("/api/v1/session", http.HandlerFunc(
+	http.Handle("/api/v1/session", metrics.Instrument("session", http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			switch r.Method {
 			case http.MethodPost:
@@ -36,11 +45,25 @@ func registerSessionHandlers(handlersEnv handlers.Environment) {
 			default:
 				handlersEnv.ErrorMethodNotAllowed(w, r)
 			}
-		}))
+		})))
+}
+
+func registerSessionsHandlers(handlersEnv handlers.Environment) {
+	http.Handle("/api/v1/sessions", metrics.Instrument("sessions", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				handlersEnv.Sessions(w, r)
+			case http.MethodDelete:
+				handlersEnv.RevokeSession(w, r)
+			default:
+				handlersEnv.ErrorMethodNotAllowed(w, r)
+			}
+		})))
 }
 
 func registerAvatarHandlers(handlersEnv handlers.Environment) {
-	http.Handle("/api/v1/avatar", http.HandlerFunc(
+	http.Handle("/api/v1/avatar", metrics.Instrument("avatar", http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			switch r.Method {
 			case http.MethodPost:
@@ -48,11 +71,11 @@ func registerAvatarHandlers(handlersEnv handlers.Environment) {
 			default:
 				handlersEnv.ErrorMethodNotAllowed(w, r)
 			}
-		}))
+		})))
 }
 
 func registerUserHandlers(handlersEnv handlers.Environment) {
-	http.Handle("/api/v1/user", http.HandlerFunc(
+	http.Handle("/api/v1/user", metrics.Instrument("user", http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			switch r.Method {
 			case http.MethodGet:
@@ -69,10 +92,75 @@ func registerUserHandlers(handlersEnv handlers.Environment) {
 					handlersEnv.RegistrationRegular(w, r)
 				}
 				return
+			case http.MethodPatch:
+				handlersEnv.UpdateProfile(w, r)
+			case http.MethodDelete:
+				handlersEnv.DeleteAccount(w, r)
+			default:
+				handlersEnv.ErrorMethodNotAllowed(w, r)
+			}
+		})))
+}
+
+func registerUserUpgradeHandlers(handlersEnv handlers.Environment) {
+	http.Handle("/api/v1/user/upgrade", metrics.Instrument("user_upgrade", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost:
+				handlersEnv.UpgradeDisposableUser(w, r)
+			default:
+				handlersEnv.ErrorMethodNotAllowed(w, r)
+			}
+		})))
+}
+
+func registerOAuthHandlers(handlersEnv handlers.Environment) {
+	http.Handle("/api/v1/oauth/login", metrics.Instrument("oauth_login", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				handlersEnv.OAuthLogin(w, r)
+			default:
+				handlersEnv.ErrorMethodNotAllowed(w, r)
+			}
+		})))
+	http.Handle("/api/v1/oauth/callback", metrics.Instrument("oauth_callback", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				handlersEnv.OAuthCallback(w, r)
+			default:
+				handlersEnv.ErrorMethodNotAllowed(w, r)
+			}
+		})))
+}
+
+func registerInternalHandlers(handlersEnv handlers.Environment) {
+	http.Handle("/api/v1/internal/game_ended", metrics.Instrument("internal_game_ended", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost:
+				handlersEnv.GameEnded(w, r)
+			default:
+				handlersEnv.ErrorMethodNotAllowed(w, r)
+			}
+		})))
+}
+
+func registerFormationsHandlers(handlersEnv handlers.Environment) {
+	http.Handle("/api/v1/formations", metrics.Instrument("formations", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				handlersEnv.Formations(w, r)
+			case http.MethodPost:
+				handlersEnv.SaveFormation(w, r)
+			case http.MethodDelete:
+				handlersEnv.DeleteFormation(w, r)
 			default:
 				handlersEnv.ErrorMethodNotAllowed(w, r)
 			}
-		}))
+		})))
 }
 
 func main() {
@@ -90,36 +178,169 @@ func main() {
 		"images-root",
 		"/var/www/media/images",
 		"the folder in which the downloaded avatars of users will be saved")
+	env.Config.ImagesURL = flag.String(
+		"images-url",
+		"/media/images/",
+		"address at which Nginx serves --images-root")
+	avatarStorage := flag.String("avatar-storage", "local", "where avatars are stored: 'local' - in --images-root, 's3' - in an S3 compatible bucket")
+	s3Endpoint := flag.String("s3-endpoint", "https://s3.amazonaws.com", "S3 compatible storage address like 'http://minio:9000'")
+	s3Region := flag.String("s3-region", "us-east-1", "region of the bucket, used in request signatures")
+	s3Bucket := flag.String("s3-bucket", "", "bucket for avatars, objects must be publicly readable")
+	s3AccessKey := flag.String("s3-access-key", os.Getenv("AWS_ACCESS_KEY_ID"), "access key, defaults to $AWS_ACCESS_KEY_ID")
+	s3SecretKey := flag.String("s3-secret-key", os.Getenv("AWS_SECRET_ACCESS_KEY"), "secret key, defaults to $AWS_SECRET_ACCESS_KEY")
+	s3PublicURL := flag.String("s3-public-url", "", "address at which clients download avatars like a CDN before the bucket, empty - <s3-endpoint>/<s3-bucket>")
+	env.Config.SessionLifetime = flag.Duration(
+		"session-lifetime",
+		7*24*time.Hour,
+		"how long an idle session stays valid, every request extends it")
+	env.Config.DisposableUserTTL = flag.Duration(
+		"disposable-user-ttl",
+		24*time.Hour,
+		"disposable users without requests for this long are deleted and their logins become free, 0 disables the cleanup")
+	migrateOnStart := flag.Bool(
+		"migrate-on-start",
+		true,
+		"apply pending schema migrations on start, otherwise refuse to start with an outdated schema")
+	env.Config.InternalToken = flag.String(
+		"internal-token",
+		os.Getenv("INTERNAL_TOKEN"),
+		"token of game servers in 'Authorization: Bearer' for /api/v1/internal/, defaults to $INTERNAL_TOKEN; empty disables these requests")
+	env.Config.ClientIPHeader = flag.String(
+		"client-ip-header",
+		"",
+		"header with the client address set by the reverse proxy: 'X-Real-IP' in the Dockerfile, for Nginx with "+
+			"'proxy_set_header X-Real-IP $remote_addr'. Set only behind a proxy that overwrites it, clients can forge it otherwise. "+
+			"Empty - use the connection address, behind a proxy all clients then share one rate limit")
+	ipAttemptsPerMinute := flag.Int("ip-attempts-per-minute", 20, "login and registration attempts per minute from one address, 0 - unlimited")
+	loginAttemptsPerMinute := flag.Int("login-attempts-per-minute", 10, "login attempts per minute into one account, 0 - unlimited")
+	failureBackoff := flag.Duration("failure-backoff", time.Second, "pause after a wrong password, doubles with every failure in a row")
+	maxFailureBackoff := flag.Duration("max-failure-backoff", time.Minute, "upper limit of the pause after a wrong password")
+	lockoutThreshold := flag.Int("lockout-threshold", 10, "wrong passwords in a row that lock the account, 0 - never lock")
+	lockoutDuration := flag.Duration("lockout-duration", 15*time.Minute, "how long a locked account rejects logins")
+	env.Config.OAuthRedirectURL = flag.String(
+		"oauth-redirect-url",
+		"https://rpsarena.ru/api/v1/oauth/callback",
+		"external address of /api/v1/oauth/callback, must be registered at every provider")
+	env.Config.OAuthSuccessURL = flag.String("oauth-success-url", "/", "where the user is redirected after login with a provider")
+	vkClientID := flag.String("vk-client-id", "", "VK application id, empty - login with VK is disabled")
+	vkClientSecret := flag.String("vk-client-secret", os.Getenv("VK_CLIENT_SECRET"), "VK application secret key, defaults to $VK_CLIENT_SECRET")
+	oidcName := flag.String("oidc-name", "oidc", "provider name for /api/v1/oauth/login?provider=, must differ from 'vk'")
+	oidcIssuer := flag.String("oidc-issuer", "", "OpenID Connect issuer like 'https://accounts.google.com', empty - disabled")
+	oidcClientID := flag.String("oidc-client-id", "", "OpenID Connect client id")
+	oidcClientSecret := flag.String("oidc-client-secret", os.Getenv("OIDC_CLIENT_SECRET"), "OpenID Connect client secret, defaults to $OIDC_CLIENT_SECRET")
+	logLevel := flag.String("log-level", "info", "minimal log level: 'debug', 'info', 'warn' or 'error'")
+	logFormat := flag.String("log-format", "json", "log output: 'json' or human readable 'console'")
 	flag.Parse()
+	err := logging.Setup(*logLevel, *logFormat)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid logging flags")
+	}
+
+	switch *avatarStorage {
+	case "local":
+		env.AvatarStorage = avatar.NewLocalStorage(*env.Config.ImagesRoot, *env.Config.ImagesURL)
+	case "s3":
+		if *s3Bucket == "" {
+			log.Fatal().Msg("--s3-bucket is required for --avatar-storage='s3'")
+		}
+		env.AvatarStorage = avatar.NewS3Storage(*s3Endpoint, *s3Region, *s3Bucket, *s3AccessKey, *s3SecretKey, *s3PublicURL)
+	default:
+		log.Fatal().Str("avatar-storage", *avatarStorage).Msg("unknown avatar storage")
+	}
+
+	env.OAuthProviders = map[string]oauth.Provider{}
+	if *vkClientID != "" {
+		env.OAuthProviders["vk"] = oauth.NewVK(*vkClientID, *vkClientSecret)
+	}
+	if *oidcIssuer != "" {
+		// имя попадает в cookie "OAuthState" до ':', поэтому двоеточие в нём недопустимо.
+		if *oidcName == "" || strings.Contains(*oidcName, ":") {
+			log.Fatal().Str("oidc-name", *oidcName).Msg("--oidc-name must be non-empty and without ':'")
+		}
+		// иначе один провайдер молча подменил бы другой.
+		if _, used := env.OAuthProviders[*oidcName]; used {
+			log.Fatal().Str("oidc-name", *oidcName).Msg("--oidc-name is already used by another provider")
+		}
+		// адреса провайдера читаются один раз при запуске.
+		env.OAuthProviders[*oidcName], err = oauth.DiscoverOIDC(*oidcIssuer, *oidcClientID, *oidcClientSecret)
+		if err != nil {
+			log.Fatal().Err(err).Msg("can not discover OpenID Connect provider")
+		}
+	}
+
+	// неудачи старше часа забываются.
+	env.IPLimiter = limiter.NewMemory(limiter.Config{
+		PerMinute:  *ipAttemptsPerMinute,
+		Backoff:    *failureBackoff,
+		MaxBackoff: *maxFailureBackoff,
+		Window:     time.Hour,
+	})
+	env.LoginLimiter = limiter.NewMemory(limiter.Config{
+		PerMinute:        *loginAttemptsPerMinute,
+		Backoff:          *failureBackoff,
+		MaxBackoff:       *maxFailureBackoff,
+		LockoutThreshold: *lockoutThreshold,
+		LockoutDuration:  *lockoutDuration,
+		Window:           time.Hour,
+	})
 
 	// подключаемся к базе.
-	var err error
 	handlersEnv := handlers.Environment(env)
 	handlersEnv.DB, err = accessor.ConnectToDatabase(*handlersEnv.Config.PostgresPath)
 	if err != nil {
-		log.Fatal(errors.Wrap(err, "accessor.ConnectToDatabase: "))
+		log.Fatal().Err(errors.Wrap(err, "accessor.ConnectToDatabase: ")).Msg("can not connect to database")
+	}
+
+	// подкоманда: authorization_server migrate [up|down|status|<version>], сервер не запускается.
+	if flag.Arg(0) == "migrate" {
+		err = migrate(handlersEnv.DB, flag.Args()[1:])
+		_ = handlersEnv.DB.Close()
+		if err != nil {
+			log.Fatal().Err(err).Msg("migration failed")
+		}
+		return
+	}
+	if *migrateOnStart {
+		err = handlersEnv.DB.MigrateTo(accessor.LatestSchemaVersion())
+	} else {
+		err = handlersEnv.DB.CheckSchemaVersion()
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("unsuitable database schema")
 	}
 	err = handlersEnv.DB.InitDatabase()
 	if err != nil {
-		log.Fatal(err)
+		log.Fatal().Err(err).Msg("can not prepare database")
 	}
 
 	defer func() {
 		err := handlersEnv.DB.Close()
 		if err != nil {
-			log.Fatal("failed to close Database connection: " + err.Error())
+			log.Fatal().Err(err).Msg("failed to close Database connection")
 		}
 	}()
 
+	// уборка одноразовых пользователей, проход раз в десятую часть их срока жизни.
+	if *env.Config.DisposableUserTTL > 0 {
+		go janitor.NewJanitor(handlersEnv.DB, *env.Config.DisposableUserTTL, *env.Config.DisposableUserTTL/10).Run()
+	}
+
 	// регистрируем обработчики запросов с логикой сервера.
 	registerUserHandlers(handlersEnv)
+	registerUserUpgradeHandlers(handlersEnv)
 	registerUsersHandlers(handlersEnv)
 	registerSessionHandlers(handlersEnv)
+	registerSessionsHandlers(handlersEnv)
 	registerAvatarHandlers(handlersEnv)
+	registerFormationsHandlers(handlersEnv)
+	registerOAuthHandlers(handlersEnv)
+	registerInternalHandlers(handlersEnv)
+	http.Handle("/metrics", promhttp.Handler())
 
 	// начинаем слушать порт.
-	fmt.Println("starting server at :" + *env.Config.ListeningPort)
-	log.Println(http.ListenAndServe(":"+*env.Config.ListeningPort, nil))
+	log.Info().Msg("starting server at :" + *env.Config.ListeningPort)
+	err = http.ListenAndServe(":"+*env.Config.ListeningPort, logging.Middleware(http.DefaultServeMux))
+	log.Error().Err(err).Msg("server stopped")
 
 	return
 }
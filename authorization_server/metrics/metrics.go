@@ -0,0 +1,82 @@
+// Метрики сервера авторизации в формате Prometheus, отдаются по /metrics.
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "authorization_http_requests_total",
+		Help: "HTTP requests by handler and response status.",
+	}, []string{"handler", "status"})
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "authorization_http_request_duration_seconds",
+		Help:    "HTTP request handling time by handler.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"handler"})
+	QueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "authorization_db_query_duration_seconds",
+		Help:    "Database query time by prepared statement.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"statement"})
+	Registrations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "authorization_registrations_total",
+		Help: "Successful registrations by kind: regular, temporary or oauth.",
+	}, []string{"kind"})
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "authorization_logins_total",
+		Help: "Login attempts by result: success, oauth or wrong_login_or_password.",
+	}, []string{"result"})
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "authorization_rate_limited_total",
+		Help: "Login and registration attempts rejected with 429 by limiter: ip or login.",
+	}, []string{"limiter"})
+	DisposableUsersDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "authorization_disposable_users_deleted_total",
+		Help: "Deleted disposable users by reason: logout, inactivity or game_end.",
+	}, []string{"reason"})
+	DisposableUsersUpgraded = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "authorization_disposable_users_upgraded_total",
+		Help: "Disposable users turned into regular ones.",
+	})
+	AccountsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "authorization_accounts_deleted_total",
+		Help: "Accounts deleted by their owners.",
+	})
+)
+
+// вызывается через defer в начале каждой функции accessor:
+// defer metrics.ObserveQuery("SelectUserByLogin", time.Now())
+func ObserveQuery(statement string, start time.Time) {
+	QueryDuration.WithLabelValues(statement).Observe(time.Since(start).Seconds())
+	return
+}
+
+// запоминает код ответа, который handler передал в WriteHeader.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// оборачивает handler, считая запросы и время их обработки под именем name.
+func Instrument(name string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		Requests.WithLabelValues(name, strconv.Itoa(recorder.status)).Inc()
+		RequestDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"strconv"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/authorization_server/accessor"
+)
+
+// подкоманда migrate:
+//
+//	migrate, migrate up - применить все миграции
+//	migrate down        - откатить последнюю миграцию
+//	migrate status      - версия базы и бинарника
+//	migrate 2           - перейти к версии 2 в любую сторону, 0 - удалить все таблицы
+func migrate(db accessor.DB, args []string) (err error) {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return
+	}
+	command := "up"
+	if len(args) != 0 {
+		command = args[0]
+	}
+	target := version
+	switch command {
+	case "up":
+		target = accessor.LatestSchemaVersion()
+	case "down":
+		target = version - 1
+	case "status":
+	default:
+		target, err = strconv.Atoi(command)
+		if err != nil {
+			err = errors.New("unknown migrate command '" + command + "', expected up, down, status or version")
+			return
+		}
+	}
+	if target != version {
+		err = db.MigrateTo(target)
+		if err != nil {
+			return
+		}
+		log.Info().Int("from", version).Int("to", target).Msg("database migrated")
+	}
+	log.Info().Int("database", target).Int("binary", accessor.LatestSchemaVersion()).Msg("schema version")
+	return
+}
@@ -0,0 +1,60 @@
+// Вход через внешние аккаунты по OAuth 2.0 authorization code flow:
+// клиент уходит на страницу провайдера, возвращается с кодом, сервер меняет код на токен
+// и по токену узнаёт, кто вошёл. Провайдер - ВКонтакте или любой OpenID Connect.
+
+package oauth
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// пользователь у провайдера.
+type Identity struct {
+	// постоянный id у провайдера, по нему аккаунт провайдера связан с "user".
+	Subject string
+	// предлагаемый логин для нового пользователя, может быть пустым.
+	Login string
+}
+
+type Provider interface {
+	// адрес страницы входа провайдера. state вернётся в redirectURL без изменений.
+	AuthCodeURL(state string, redirectURL string) (address string)
+	// меняет код из redirectURL на пользователя. redirectURL тот же, что и в AuthCodeURL.
+	Exchange(code string, redirectURL string) (identity Identity, err error)
+}
+
+// ответы провайдеров маленькие, обрезанный ответ не разберётся как JSON.
+const maxResponseSize = 1 << 20
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// выполняет запрос и разбирает JSON ответа в target.
+// Ответ не 200 - ошибка, тело попадает в текст ошибки: провайдеры пишут туда причину.
+func doJSON(client *http.Client, request *http.Request, target interface{}) (err error) {
+	request.Header.Set("Accept", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		return
+	}
+	defer func() { _ = response.Body.Close() }()
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return
+	}
+	if response.StatusCode != http.StatusOK {
+		err = errors.New(request.URL.Host + request.URL.Path + ": " + strconv.Itoa(response.StatusCode) + " " + string(body))
+		return
+	}
+	err = json.Unmarshal(body, target)
+	err = errors.Wrap(err, "json.Unmarshal: ")
+	return
+}
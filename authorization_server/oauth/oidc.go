@@ -0,0 +1,124 @@
+package oauth
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Любой провайдер OpenID Connect: Google, Яндекс, Keycloak и подобные.
+// Пользователь узнаётся через userinfo endpoint по access token, полученному напрямую
+// от провайдера по TLS, поэтому подпись id_token не проверяется.
+type OIDC struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scopes       []string
+	Client       *http.Client
+}
+
+// читает адреса из <issuer>/.well-known/openid-configuration.
+func DiscoverOIDC(issuer string, clientID string, clientSecret string) (provider *OIDC, err error) {
+	provider = &OIDC{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"openid", "profile"},
+		Client:       newHTTPClient(),
+	}
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return
+	}
+	configuration := struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	}{}
+	err = doJSON(provider.Client, request, &configuration)
+	if err == nil && (configuration.AuthorizationEndpoint == "" || configuration.TokenEndpoint == "" ||
+		configuration.UserInfoEndpoint == "") {
+		err = errors.New("authorization, token or userinfo endpoint is missing")
+	}
+	if err != nil {
+		err = errors.Wrap(err, "openid configuration of '"+issuer+"': ")
+		return
+	}
+	provider.AuthURL = configuration.AuthorizationEndpoint
+	provider.TokenURL = configuration.TokenEndpoint
+	provider.UserInfoURL = configuration.UserInfoEndpoint
+	return
+}
+
+func (oidc *OIDC) AuthCodeURL(state string, redirectURL string) (address string) {
+	query := url.Values{
+		"client_id":     {oidc.ClientID},
+		"redirect_uri":  {redirectURL},
+		"response_type": {"code"},
+		"scope":         {strings.Join(oidc.Scopes, " ")},
+		"state":         {state},
+	}
+	separator := "?"
+	if strings.Contains(oidc.AuthURL, "?") {
+		separator = "&"
+	}
+	address = oidc.AuthURL + separator + query.Encode()
+	return
+}
+
+func (oidc *OIDC) Exchange(code string, redirectURL string) (identity Identity, err error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {oidc.ClientID},
+		"client_secret": {oidc.ClientSecret},
+	}
+	request, err := http.NewRequest(http.MethodPost, oidc.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	err = doJSON(oidc.Client, request, &token)
+	if err == nil && token.AccessToken == "" {
+		err = errors.New("no access_token in response")
+	}
+	if err != nil {
+		err = errors.Wrap(err, "oidc token: ")
+		return
+	}
+
+	request, err = http.NewRequest(http.MethodGet, oidc.UserInfoURL, nil)
+	if err != nil {
+		return
+	}
+	request.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	userInfo := struct {
+		Subject           string `json:"sub"`
+		PreferredUsername string `json:"preferred_username"`
+		Nickname          string `json:"nickname"`
+		Name              string `json:"name"`
+	}{}
+	err = doJSON(oidc.Client, request, &userInfo)
+	if err == nil && userInfo.Subject == "" {
+		err = errors.New("no sub in response")
+	}
+	if err != nil {
+		err = errors.Wrap(err, "oidc userinfo: ")
+		return
+	}
+	identity.Subject = userInfo.Subject
+	for _, login := range []string{userInfo.PreferredUsername, userInfo.Nickname, userInfo.Name} {
+		if login != "" {
+			identity.Login = login
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package oauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// провайдер OpenID Connect в памяти: код "code" меняется на токен, userinfo отвечает userInfo.
+type fakeOIDC struct {
+	url      string
+	userInfo string
+}
+
+func (f *fakeOIDC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/issuer/.well-known/openid-configuration":
+		_, _ = w.Write([]byte(`{
+			"issuer": "` + f.url + `/issuer",
+			"authorization_endpoint": "` + f.url + `/authorize?prompt=login",
+			"token_endpoint": "` + f.url + `/token",
+			"userinfo_endpoint": "` + f.url + `/userinfo"
+		}`))
+	case "/token":
+		if r.Method != http.MethodPost || r.PostFormValue("grant_type") != "authorization_code" ||
+			r.PostFormValue("client_id") != "id" || r.PostFormValue("client_secret") != "secret" ||
+			r.PostFormValue("redirect_uri") != "https://example.com/callback" || r.PostFormValue("code") != "code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"token","token_type":"Bearer","id_token":"unused"}`))
+	case "/userinfo":
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(f.userInfo))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeOIDC(t *testing.T, userInfo string) (server *httptest.Server, provider *OIDC) {
+	fake := &fakeOIDC{userInfo: userInfo}
+	server = httptest.NewServer(fake)
+	fake.url = server.URL
+	provider, err := DiscoverOIDC(server.URL+"/issuer/", "id", "secret")
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestDiscoverOIDC(t *testing.T) {
+	server, provider := newFakeOIDC(t, "")
+	defer server.Close()
+
+	if provider.TokenURL != server.URL+"/token" || provider.UserInfoURL != server.URL+"/userinfo" {
+		t.Fatalf("endpoints %+v", provider)
+	}
+	// у адреса входа уже есть параметры, state добавляется к ним.
+	parsed, err := url.Parse(provider.AuthCodeURL("state", "https://example.com/callback"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if parsed.Path != "/authorize" || query.Get("prompt") != "login" || query.Get("state") != "state" ||
+		query.Get("scope") != "openid profile" || query.Get("client_id") != "id" {
+		t.Fatalf("login address %s", parsed)
+	}
+
+	if _, err = DiscoverOIDC(server.URL+"/unknown", "id", "secret"); err == nil {
+		t.Fatal("missing configuration is accepted")
+	}
+}
+
+func TestOIDCExchange(t *testing.T) {
+	cases := []struct {
+		userInfo string
+		login    string
+	}{
+		{`{"sub":"s1","preferred_username":"ivan","nickname":"vanya","name":"Иван Петров"}`, "ivan"},
+		{`{"sub":"s1","name":"Иван Петров"}`, "Иван Петров"},
+		{`{"sub":"s1"}`, ""},
+	}
+	for _, c := range cases {
+		server, provider := newFakeOIDC(t, c.userInfo)
+		identity, err := provider.Exchange("code", "https://example.com/callback")
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if identity.Subject != "s1" || identity.Login != c.login {
+			t.Fatalf("identity %+v, want subject s1 and login %q", identity, c.login)
+		}
+	}
+}
+
+func TestOIDCExchangeError(t *testing.T) {
+	server, provider := newFakeOIDC(t, `{"preferred_username":"ivan"}`)
+	defer server.Close()
+
+	if _, err := provider.Exchange("wrong", "https://example.com/callback"); err == nil {
+		t.Fatal("invalid code is accepted")
+	}
+	if _, err := provider.Exchange("code", "https://example.com/callback"); err == nil {
+		t.Fatal("userinfo without sub is accepted")
+	}
+}
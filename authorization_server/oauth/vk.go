@@ -0,0 +1,124 @@
+package oauth
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// версия VK API, под которую написан разбор ответов.
+const vkAPIVersion = "5.131"
+
+// ВКонтакте: OAuth 2.0 без OpenID Connect, пользователь узнаётся через метод users.get.
+// https://dev.vk.com/api/access-token/authcode-flow-user
+type VK struct {
+	ClientID     string
+	ClientSecret string
+	// адреса меняются только ради тестового провайдера.
+	AuthURL  string
+	TokenURL string
+	APIURL   string
+	Client   *http.Client
+}
+
+func NewVK(clientID string, clientSecret string) (provider *VK) {
+	provider = &VK{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		AuthURL:      "https://oauth.vk.com/authorize",
+		TokenURL:     "https://oauth.vk.com/access_token",
+		APIURL:       "https://api.vk.com/method",
+		Client:       newHTTPClient(),
+	}
+	return
+}
+
+func (vk *VK) AuthCodeURL(state string, redirectURL string) (address string) {
+	query := url.Values{
+		"client_id":     {vk.ClientID},
+		"redirect_uri":  {redirectURL},
+		"response_type": {"code"},
+		"state":         {state},
+		"v":             {vkAPIVersion},
+	}
+	address = vk.AuthURL + "?" + query.Encode()
+	return
+}
+
+func (vk *VK) Exchange(code string, redirectURL string) (identity Identity, err error) {
+	query := url.Values{
+		"client_id":     {vk.ClientID},
+		"client_secret": {vk.ClientSecret},
+		"redirect_uri":  {redirectURL},
+		"code":          {code},
+	}
+	request, err := http.NewRequest(http.MethodGet, vk.TokenURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+		UserID      int64  `json:"user_id"`
+	}{}
+	err = doJSON(vk.Client, request, &token)
+	if err == nil && (token.AccessToken == "" || token.UserID == 0) {
+		err = errors.New("no access_token or user_id in response")
+	}
+	if err != nil {
+		err = errors.Wrap(err, "vk access_token: ")
+		return
+	}
+	identity.Subject = strconv.FormatInt(token.UserID, 10)
+
+	identity.Login, err = vk.userName(token.AccessToken)
+	err = errors.Wrap(err, "vk users.get: ")
+	return
+}
+
+// короткий адрес страницы, если пользователь его задал, иначе имя и фамилия.
+func (vk *VK) userName(accessToken string) (name string, err error) {
+	query := url.Values{
+		"access_token": {accessToken},
+		"fields":       {"screen_name"},
+		"v":            {vkAPIVersion},
+	}
+	request, err := http.NewRequest(http.MethodGet, vk.APIURL+"/users.get?"+query.Encode(), nil)
+	if err != nil {
+		return
+	}
+	// ошибки API приходят с кодом 200 в поле "error".
+	answer := struct {
+		Response []struct {
+			ID         int64  `json:"id"`
+			FirstName  string `json:"first_name"`
+			LastName   string `json:"last_name"`
+			ScreenName string `json:"screen_name"`
+		} `json:"response"`
+		Error *struct {
+			Message string `json:"error_msg"`
+		} `json:"error"`
+	}{}
+	err = doJSON(vk.Client, request, &answer)
+	if err != nil {
+		return
+	}
+	if answer.Error != nil {
+		err = errors.New(answer.Error.Message)
+		return
+	}
+	if len(answer.Response) == 0 {
+		err = errors.New("empty response")
+		return
+	}
+	user := answer.Response[0]
+	// у пользователей без короткого адреса screen_name равен "id<номер>".
+	if user.ScreenName != "" && user.ScreenName != "id"+strconv.FormatInt(user.ID, 10) {
+		name = user.ScreenName
+		return
+	}
+	name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	return
+}
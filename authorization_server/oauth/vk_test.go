@@ -0,0 +1,116 @@
+package oauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// ВКонтакте в памяти: код "code" меняется на токен пользователя 42, users.get отвечает user.
+type fakeVK struct {
+	user string
+}
+
+func (f *fakeVK) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/access_token":
+		if query.Get("client_id") != "id" || query.Get("client_secret") != "secret" ||
+			query.Get("redirect_uri") != "https://example.com/callback" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		if query.Get("code") != "code" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"token","expires_in":86400,"user_id":42}`))
+	case "/method/users.get":
+		if query.Get("access_token") != "token" || query.Get("v") != vkAPIVersion {
+			_, _ = w.Write([]byte(`{"error":{"error_code":5,"error_msg":"User authorization failed"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(f.user))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeVK(user string) (server *httptest.Server, provider *VK) {
+	server = httptest.NewServer(&fakeVK{user: user})
+	provider = NewVK("id", "secret")
+	provider.AuthURL = server.URL + "/authorize"
+	provider.TokenURL = server.URL + "/access_token"
+	provider.APIURL = server.URL + "/method"
+	return
+}
+
+func TestVKAuthCodeURL(t *testing.T) {
+	address := NewVK("id", "secret").AuthCodeURL("state", "https://example.com/callback")
+	parsed, err := url.Parse(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(address, "https://oauth.vk.com/authorize?") {
+		t.Fatalf("address %q", address)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != "id" || query.Get("state") != "state" || query.Get("response_type") != "code" ||
+		query.Get("redirect_uri") != "https://example.com/callback" {
+		t.Fatalf("query %v", query)
+	}
+}
+
+func TestVKExchange(t *testing.T) {
+	cases := []struct {
+		user  string
+		login string
+	}{
+		{`{"response":[{"id":42,"first_name":"Иван","last_name":"Петров","screen_name":"ivan"}]}`, "ivan"},
+		// короткого адреса нет: screen_name равен "id<номер>".
+		{`{"response":[{"id":42,"first_name":"Иван","last_name":"Петров","screen_name":"id42"}]}`, "Иван Петров"},
+	}
+	for _, c := range cases {
+		server, provider := newFakeVK(c.user)
+		identity, err := provider.Exchange("code", "https://example.com/callback")
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if identity.Subject != "42" || identity.Login != c.login {
+			t.Fatalf("identity %+v, want subject 42 and login %q", identity, c.login)
+		}
+	}
+}
+
+func TestVKExchangeError(t *testing.T) {
+	server, provider := newFakeVK(`{"response":[]}`)
+	defer server.Close()
+
+	if _, err := provider.Exchange("wrong", "https://example.com/callback"); err == nil {
+		t.Fatal("invalid code is accepted")
+	}
+	if _, err := provider.Exchange("code", "https://example.com/callback"); err == nil {
+		t.Fatal("empty users.get response is accepted")
+	}
+	provider.ClientSecret = "wrong"
+	if _, err := provider.Exchange("code", "https://example.com/callback"); err == nil {
+		t.Fatal("invalid client secret is accepted")
+	}
+}
+
+// ошибки VK API приходят с кодом 200.
+func TestVKUsersGetError(t *testing.T) {
+	server, provider := newFakeVK("")
+	defer server.Close()
+
+	_, err := provider.userName("expired")
+	if err == nil || !strings.Contains(err.Error(), "User authorization failed") {
+		t.Fatalf("API error is not returned: %v", err)
+	}
+}
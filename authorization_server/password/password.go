@@ -0,0 +1,117 @@
+// Хеширование паролей argon2id с солью для каждого пользователя.
+// Хеш хранится в формате PHC вместе с параметрами:
+// $argon2id$v=19$m=19456,t=2,p=1$<соль base64>$<хеш base64>,
+// поэтому параметры можно усилить, не ломая уже сохранённые хеши.
+
+package password
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+// параметры argon2id, рекомендованные OWASP.
+type Params struct {
+	Memory     uint32 // KiB
+	Iterations uint32
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// параметры новых хешей. Хеш с другими параметрами пересчитывается при следующем входе.
+var Current = Params{
+	Memory:     19 * 1024,
+	Iterations: 2,
+	Threads:    1,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+var ErrInvalidHash = errors.New("invalid password hash format")
+
+// возвращает хеш пароля с новой случайной солью.
+func Hash(password string) (encoded string, err error) {
+	salt := make([]byte, Current.SaltLength)
+	_, err = rand.Read(salt)
+	if err != nil {
+		err = errors.Wrap(err, "in rand.Read salt: ")
+		return
+	}
+	key := argon2.IDKey([]byte(password), salt, Current.Iterations, Current.Memory, Current.Threads, Current.KeyLength)
+	encoded = fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		Current.Memory, Current.Iterations, Current.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	return
+}
+
+// проверяет пароль. needsRehash - хеш верный, но устарел: его надо заменить на Hash(password).
+// Кроме argon2id понимает старые хеши: sha256 без соли в hex.
+func Verify(password string, encoded string) (match bool, needsRehash bool, err error) {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		match, err = verifyLegacy(password, encoded)
+		needsRehash = match
+		return
+	}
+	params, salt, key, err := decode(encoded)
+	if err != nil {
+		return
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Threads, params.KeyLength)
+	match = subtle.ConstantTimeCompare(computed, key) == 1
+	needsRehash = match && params != Current
+	return
+}
+
+func verifyLegacy(password string, encoded string) (match bool, err error) {
+	key, err := hex.DecodeString(encoded)
+	if err != nil || len(key) != sha256.Size {
+		err = ErrInvalidHash
+		return
+	}
+	computed := sha256.Sum256([]byte(password))
+	match = subtle.ConstantTimeCompare(computed[:], key) == 1
+	return
+}
+
+func decode(encoded string) (params Params, salt []byte, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=19456,t=2,p=1", соль, хеш
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		err = ErrInvalidHash
+		return
+	}
+	var version int
+	_, err = fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		err = ErrInvalidHash
+		return
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Threads)
+	// argon2.IDKey паникует при t=0 или p=0.
+	if err != nil || params.Iterations == 0 || params.Threads == 0 {
+		err = ErrInvalidHash
+		return
+	}
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		err = ErrInvalidHash
+		return
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		err = ErrInvalidHash
+		return
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return
+}
@@ -0,0 +1,113 @@
+package password
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// хеш с заданными параметрами, как его записал бы Hash.
+func encode(params Params, password string, salt []byte) string {
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Threads, params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		params.Memory, params.Iterations, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestHashVerify(t *testing.T) {
+	encoded, err := Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, needsRehash, err := Verify("correct horse", encoded)
+	if err != nil || !match || needsRehash {
+		t.Fatalf("right password: match %v, needsRehash %v, %v", match, needsRehash, err)
+	}
+	match, needsRehash, err = Verify("correct horse!", encoded)
+	if err != nil || match || needsRehash {
+		t.Fatalf("wrong password: match %v, needsRehash %v, %v", match, needsRehash, err)
+	}
+
+	// соль своя у каждого хеша.
+	other, err := Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == encoded {
+		t.Fatal("two hashes of the same password are equal")
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	sum := sha256.Sum256([]byte("correct horse"))
+	legacy := hex.EncodeToString(sum[:])
+
+	match, needsRehash, err := Verify("correct horse", legacy)
+	if err != nil || !match || !needsRehash {
+		t.Fatalf("right password: match %v, needsRehash %v, %v", match, needsRehash, err)
+	}
+	match, needsRehash, err = Verify("correct horse!", legacy)
+	if err != nil || match || needsRehash {
+		t.Fatalf("wrong password: match %v, needsRehash %v, %v", match, needsRehash, err)
+	}
+}
+
+func TestVerifyWeakerParams(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	weaker := []Params{
+		{Memory: 8 * 1024, Iterations: Current.Iterations, Threads: Current.Threads, KeyLength: Current.KeyLength},
+		{Memory: Current.Memory, Iterations: 1, Threads: Current.Threads, KeyLength: Current.KeyLength},
+		{Memory: Current.Memory, Iterations: Current.Iterations, Threads: Current.Threads, KeyLength: 16},
+	}
+	for _, params := range weaker {
+		encoded := encode(params, "correct horse", salt)
+		match, needsRehash, err := Verify("correct horse", encoded)
+		if err != nil || !match || !needsRehash {
+			t.Fatalf("%+v: match %v, needsRehash %v, %v", params, match, needsRehash, err)
+		}
+		// неверный пароль не пересчитывается.
+		match, needsRehash, err = Verify("wrong", encoded)
+		if err != nil || match || needsRehash {
+			t.Fatalf("%+v, wrong password: match %v, needsRehash %v, %v", params, match, needsRehash, err)
+		}
+	}
+
+	current := encode(Current, "correct horse", salt)
+	if match, needsRehash, err := Verify("correct horse", current); err != nil || !match || needsRehash {
+		t.Fatalf("current params: match %v, needsRehash %v, %v", match, needsRehash, err)
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	salt := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	key := base64.RawStdEncoding.EncodeToString(make([]byte, 32))
+	malformed := []string{
+		"",
+		"plain text password",
+		// sha256 неверной длины.
+		"abcdef",
+		"$argon2i$v=19$m=19456,t=2,p=1$" + salt + "$" + key,
+		"$bcrypt$2b$10$abcdefghijklmnopqrstuv",
+		"$argon2id$",
+		"$argon2id$v=19$m=19456,t=2,p=1$" + salt,
+		"$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$" + key + "$extra",
+		"$argon2id$v=16$m=19456,t=2,p=1$" + salt + "$" + key,
+		"$argon2id$version$m=19456,t=2,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=19456$" + salt + "$" + key,
+		"$argon2id$v=19$m=19456,t=0,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=19456,t=2,p=0$" + salt + "$" + key,
+		"$argon2id$v=19$m=19456,t=2,p=1$not*base64$" + key,
+		"$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$not*base64",
+		"$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$",
+	}
+	for _, encoded := range malformed {
+		match, needsRehash, err := Verify("correct horse", encoded)
+		if err == nil || match || needsRehash {
+			t.Fatalf("%q: match %v, needsRehash %v, %v", encoded, match, needsRehash, err)
+		}
+	}
+}
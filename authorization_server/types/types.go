@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // общая форма ответа сервера.
 
 //easyjson:json
@@ -15,6 +17,21 @@ type NewUserRegistration struct {
 }
 
 // Публичная информация пользователя
+// пароль, с которым одноразовый пользователь становится обычным.
+//easyjson:json
+type DisposableUserUpgrade struct {
+	Password string `json:"password"`
+}
+
+// изменения профиля через PATCH /api/v1/user, пустые поля не меняются.
+// Для смены пароля нужен текущий.
+//easyjson:json
+type ProfileUpdate struct {
+	Login           string `json:"login"`
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
 //easyjson:json
 type PublicUserInformation struct {
 	Login         string `json:"login"`
@@ -25,3 +42,35 @@ type PublicUserInformation struct {
 
 //easyjson:json
 type PublicUsersInformation []PublicUserInformation
+
+// Именованная расстановка персонажей, сохранённая пользователем для быстрого старта игры.
+// Порядок оружия тот же, что и в методе "upload_map" игрового сервера.
+//easyjson:json
+type Formation struct {
+	Name    string     `json:"name"`
+	Weapons [14]string `json:"weapons"`
+}
+
+//easyjson:json
+type Formations []Formation
+
+// Сессия пользователя на одном из его устройств.
+//easyjson:json
+type Session struct {
+	Id         int32     `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// сессия, с которой пришёл запрос.
+	Current bool `json:"current"`
+}
+
+//easyjson:json
+type Sessions []Session
+
+// Уведомление игрового сервера о конце игры, хеши сессий обоих игроков.
+//easyjson:json
+type GameEnded struct {
+	SessionTokenHashes []string `json:"sessionTokenHashes"`
+}
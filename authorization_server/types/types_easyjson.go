@@ -17,7 +17,484 @@ var (
 	_ easyjson.Marshaler
 )
 
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes(in *jlexer.Lexer, out *PublicUsersInformation) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes(in *jlexer.Lexer, out *GameEnded) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "sessionTokenHashes":
+			if in.IsNull() {
+				in.Skip()
+				out.SessionTokenHashes = nil
+			} else {
+				in.Delim('[')
+				if out.SessionTokenHashes == nil {
+					if !in.IsDelim(']') {
+						out.SessionTokenHashes = make([]string, 0, 4)
+					} else {
+						out.SessionTokenHashes = []string{}
+					}
+				} else {
+					out.SessionTokenHashes = (out.SessionTokenHashes)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 string
+					v1 = string(in.String())
+					out.SessionTokenHashes = append(out.SessionTokenHashes, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes(out *jwriter.Writer, in GameEnded) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"sessionTokenHashes\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		if in.SessionTokenHashes == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.SessionTokenHashes {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v3))
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GameEnded) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GameEnded) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GameEnded) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GameEnded) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(in *jlexer.Lexer, out *Sessions) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(Sessions, 0, 0)
+			} else {
+				*out = Sessions{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v4 Session
+			(v4).UnmarshalEasyJSON(in)
+			*out = append(*out, v4)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(out *jwriter.Writer, in Sessions) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v5, v6 := range in {
+			if v5 > 0 {
+				out.RawByte(',')
+			}
+			(v6).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Sessions) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Sessions) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Sessions) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Sessions) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(in *jlexer.Lexer, out *Session) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.Id = int32(in.Int32())
+		case "userAgent":
+			out.UserAgent = string(in.String())
+		case "createdAt":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.CreatedAt).UnmarshalJSON(data))
+			}
+		case "lastSeenAt":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.LastSeenAt).UnmarshalJSON(data))
+			}
+		case "expiresAt":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.ExpiresAt).UnmarshalJSON(data))
+			}
+		case "current":
+			out.Current = bool(in.Bool())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(out *jwriter.Writer, in Session) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int32(int32(in.Id))
+	}
+	{
+		const prefix string = ",\"userAgent\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.UserAgent))
+	}
+	{
+		const prefix string = ",\"createdAt\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Raw((in.CreatedAt).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"lastSeenAt\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Raw((in.LastSeenAt).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"expiresAt\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Raw((in.ExpiresAt).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"current\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Bool(bool(in.Current))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Session) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Session) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Session) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Session) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(in *jlexer.Lexer, out *Formations) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(Formations, 0, 0)
+			} else {
+				*out = Formations{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v7 Formation
+			(v7).UnmarshalEasyJSON(in)
+			*out = append(*out, v7)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(out *jwriter.Writer, in Formations) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v8, v9 := range in {
+			if v8 > 0 {
+				out.RawByte(',')
+			}
+			(v9).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Formations) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Formations) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Formations) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Formations) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes4(in *jlexer.Lexer, out *Formation) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "name":
+			out.Name = string(in.String())
+		case "weapons":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('[')
+				v10 := 0
+				for !in.IsDelim(']') {
+					if v10 < 14 {
+						(out.Weapons)[v10] = string(in.String())
+						v10++
+					} else {
+						in.SkipRecursive()
+					}
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes4(out *jwriter.Writer, in Formation) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"name\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"weapons\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.RawByte('[')
+		for v11 := range in.Weapons {
+			if v11 > 0 {
+				out.RawByte(',')
+			}
+			out.String(string((in.Weapons)[v11]))
+		}
+		out.RawByte(']')
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Formation) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes4(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Formation) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes4(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Formation) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes4(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Formation) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes4(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes5(in *jlexer.Lexer, out *PublicUsersInformation) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		in.Skip()
@@ -34,9 +511,9 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes(
 			*out = (*out)[:0]
 		}
 		for !in.IsDelim(']') {
-			var v1 PublicUserInformation
-			(v1).UnmarshalEasyJSON(in)
-			*out = append(*out, v1)
+			var v12 PublicUserInformation
+			(v12).UnmarshalEasyJSON(in)
+			*out = append(*out, v12)
 			in.WantComma()
 		}
 		in.Delim(']')
@@ -45,16 +522,16 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes(
 		in.Consumed()
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes(out *jwriter.Writer, in PublicUsersInformation) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes5(out *jwriter.Writer, in PublicUsersInformation) {
 	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
 		out.RawString("null")
 	} else {
 		out.RawByte('[')
-		for v2, v3 := range in {
-			if v2 > 0 {
+		for v13, v14 := range in {
+			if v13 > 0 {
 				out.RawByte(',')
 			}
-			(v3).MarshalEasyJSON(out)
+			(v14).MarshalEasyJSON(out)
 		}
 		out.RawByte(']')
 	}
@@ -63,27 +540,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes(
 // MarshalJSON supports json.Marshaler interface
 func (v PublicUsersInformation) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes5(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v PublicUsersInformation) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes5(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *PublicUsersInformation) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes5(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *PublicUsersInformation) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes5(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(in *jlexer.Lexer, out *PublicUserInformation) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes6(in *jlexer.Lexer, out *PublicUserInformation) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -120,7 +597,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes1
 		in.Consumed()
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(out *jwriter.Writer, in PublicUserInformation) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes6(out *jwriter.Writer, in PublicUserInformation) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -170,27 +647,193 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes1
 // MarshalJSON supports json.Marshaler interface
 func (v PublicUserInformation) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes6(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v PublicUserInformation) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes6(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *PublicUserInformation) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes6(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *PublicUserInformation) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes1(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes6(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes7(in *jlexer.Lexer, out *ProfileUpdate) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "login":
+			out.Login = string(in.String())
+		case "currentPassword":
+			out.CurrentPassword = string(in.String())
+		case "newPassword":
+			out.NewPassword = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(in *jlexer.Lexer, out *NewUserRegistration) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes7(out *jwriter.Writer, in ProfileUpdate) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"login\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Login))
+	}
+	{
+		const prefix string = ",\"currentPassword\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.CurrentPassword))
+	}
+	{
+		const prefix string = ",\"newPassword\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.NewPassword))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ProfileUpdate) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes7(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ProfileUpdate) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes7(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ProfileUpdate) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes7(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ProfileUpdate) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes7(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes8(in *jlexer.Lexer, out *DisposableUserUpgrade) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "password":
+			out.Password = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes8(out *jwriter.Writer, in DisposableUserUpgrade) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"password\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Password))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v DisposableUserUpgrade) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes8(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v DisposableUserUpgrade) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes8(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *DisposableUserUpgrade) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes8(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *DisposableUserUpgrade) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes8(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes9(in *jlexer.Lexer, out *NewUserRegistration) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -223,7 +866,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes2
 		in.Consumed()
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(out *jwriter.Writer, in NewUserRegistration) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes9(out *jwriter.Writer, in NewUserRegistration) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -253,27 +896,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes2
 // MarshalJSON supports json.Marshaler interface
 func (v NewUserRegistration) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes9(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v NewUserRegistration) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes9(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *NewUserRegistration) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes9(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *NewUserRegistration) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes2(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes9(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(in *jlexer.Lexer, out *ServerResponse) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes10(in *jlexer.Lexer, out *ServerResponse) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -306,7 +949,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes3
 		in.Consumed()
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(out *jwriter.Writer, in ServerResponse) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes10(out *jwriter.Writer, in ServerResponse) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -336,23 +979,23 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes3
 // MarshalJSON supports json.Marshaler interface
 func (v ServerResponse) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes10(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v ServerResponse) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242AuthorizationServerTypes10(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *ServerResponse) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes10(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *ServerResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes3(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242AuthorizationServerTypes10(l, v)
 }
@@ -0,0 +1,184 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/game_logic"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
+)
+
+// Admin ответственен за HTTP API операторов: просмотр комнат, принудительное
+// завершение игры и отключение сессии. Доступ по заголовку "Authorization: Bearer <token>".
+type Admin struct {
+	RoomsManager *game_logic.RoomsManager
+	// пустой токен отключает API.
+	Token string
+}
+
+// Фабричная функция Admin.
+func NewAdmin(roomsManager *game_logic.RoomsManager, token string) (a *Admin) {
+	a = &Admin{
+		RoomsManager: roomsManager,
+		Token:        token,
+	}
+	return
+}
+
+func writeResponse(w http.ResponseWriter, code int, message string) {
+	response, _ := types.ServerResponse{
+		Status:  http.StatusText(code),
+		Message: message,
+	}.MarshalJSON()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(response)
+	return
+}
+
+// проверяет токен и метод запроса, при ошибке сам отвечает клиенту.
+func (a *Admin) authorized(w http.ResponseWriter, r *http.Request, method string) (ok bool) {
+	_ = r.Body.Close()
+	if a.Token == "" {
+		writeResponse(w, http.StatusNotFound, "admin_api_disabled")
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) != 1 {
+		log.Warn().Str("remote_addr", r.RemoteAddr).Str("path", r.URL.Path).Msg("admin request with invalid token")
+		writeResponse(w, http.StatusUnauthorized, "invalid_admin_token")
+		return
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeResponse(w, http.StatusMethodNotAllowed, "method_not_allowed")
+		return
+	}
+	ok = true
+	return
+}
+
+// RoomsManager уже остановлен, обычно при остановке сервера.
+func stoppedResponse(w http.ResponseWriter, err error) (stopped bool) {
+	if err != game_logic.ErrStopped {
+		return
+	}
+	writeResponse(w, http.StatusServiceUnavailable, "server_is_shutting_down")
+	stopped = true
+	return
+}
+
+func roomId(w http.ResponseWriter, r *http.Request) (id game_logic.RoomId, ok bool) {
+	number, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, "invalid_room_id")
+		return
+	}
+	id, ok = game_logic.RoomId(number), true
+	return
+}
+
+// Rooms - GET /game/v1/admin/rooms, список комнат экземпляра и ждущий соперника игрок.
+func (a *Admin) Rooms(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(w, r, http.MethodGet) {
+		return
+	}
+	rooms, err := a.RoomsManager.AdminRooms()
+	if stoppedResponse(w, err) {
+		return
+	}
+	response, _ := rooms.MarshalJSON()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(response)
+	return
+}
+
+// Room - GET /game/v1/admin/room?id=<room>, описание комнаты и её карта в виде таблицы.
+func (a *Admin) Room(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(w, r, http.MethodGet) {
+		return
+	}
+	id, ok := roomId(w, r)
+	if !ok {
+		return
+	}
+	info, dump, err := a.RoomsManager.AdminDumpRoom(id)
+	if stoppedResponse(w, err) {
+		return
+	}
+	if err != nil {
+		writeResponse(w, http.StatusNotFound, "room_not_found")
+		return
+	}
+	players := ""
+	for _, player := range info.Players {
+		players += " " + strconv.Itoa(player.Role) + ":" + player.Login
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("room " + strconv.Itoa(int(info.Room)) + ", phase " + info.Phase +
+		", last activity " + info.LastActivity + ", players" + players + "\n" + dump))
+	return
+}
+
+// EndRoom - POST /game/v1/admin/room/end?id=<room>&winner=<0|1|draw>,
+// заканчивает игру с выбранным результатом, игроки получат "gameover" с причиной "admin".
+func (a *Admin) EndRoom(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(w, r, http.MethodPost) {
+		return
+	}
+	id, ok := roomId(w, r)
+	if !ok {
+		return
+	}
+	var winner *game_logic.RoleId
+	switch r.URL.Query().Get("winner") {
+	case "0":
+		role := game_logic.RoleId(0)
+		winner = &role
+	case "1":
+		role := game_logic.RoleId(1)
+		winner = &role
+	case "draw":
+	default:
+		writeResponse(w, http.StatusBadRequest, "invalid_winner")
+		return
+	}
+	err := a.RoomsManager.AdminEndRoom(id, winner)
+	if stoppedResponse(w, err) {
+		return
+	}
+	if err != nil {
+		writeResponse(w, http.StatusNotFound, "room_not_found")
+		return
+	}
+	log.Warn().Str("remote_addr", r.RemoteAddr).Uint("room", uint(id)).Str("winner", r.URL.Query().Get("winner")).
+		Msg("room ended by admin")
+	writeResponse(w, http.StatusOK, "room_ended")
+	return
+}
+
+// Kick - POST /game/v1/admin/kick?session=<session>, разрывает соединение сессии
+// (идентификатор из списка комнат). Игрок из комнаты может переподключиться.
+func (a *Admin) Kick(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(w, r, http.MethodPost) {
+		return
+	}
+	session := r.URL.Query().Get("session")
+	err := a.RoomsManager.AdminKick(session)
+	if stoppedResponse(w, err) {
+		return
+	}
+	if err != nil {
+		writeResponse(w, http.StatusNotFound, "session_not_found")
+		return
+	}
+	log.Warn().Str("remote_addr", r.RemoteAddr).Str("session", session).Msg("session kicked by admin")
+	writeResponse(w, http.StatusOK, "session_kicked")
+	return
+}
@@ -3,10 +3,12 @@ package connectionUpgrader
 import (
 	"github.com/bxcodec/faker"
 	"github.com/gorilla/websocket"
-	"log"
+	"github.com/rs/zerolog/log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/logging"
 	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
 	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/user_connection"
 )
@@ -17,6 +19,8 @@ type ConnectionUpgrader struct {
 	upgrader websocket.Upgrader
 	// Канал, в который помещаются соединения с пользователем, что бы передать их в RoomManager
 	QueueToGame chan *user_connection.UserConnection
+	// != 0 после Close(), новые соединения не принимаются. Меняется через sync/atomic.
+	closed int32
 }
 
 // Фабричная функция ConnectionUpgrader.
@@ -28,12 +32,19 @@ func NewConnectionUpgrader() (cu *ConnectionUpgrader) {
 				return true
 			},
 			EnableCompression: true,
+			Subprotocols:      types.Subprotocols,
 		},
 		QueueToGame: make(chan *user_connection.UserConnection, 50),
 	}
 	return
 }
 
+// Перестаёт принимать новые соединения, вызывается при остановке сервера.
+func (cu *ConnectionUpgrader) Close() {
+	atomic.StoreInt32(&cu.closed, 1)
+	return
+}
+
 func (cu *ConnectionUpgrader) getAnonUserInfo() (login string, avatar string, err error) {
 	username := struct {
 		UserName string `faker:"username"`
@@ -50,7 +61,18 @@ func (cu *ConnectionUpgrader) getAnonUserInfo() (login string, avatar string, er
 // Запускается в разных горутинах, только читает из класса.
 // Проводит upgrade соединения и проверку cookie полззователя.
 func (cu *ConnectionUpgrader) HTTPEntryPoint(w http.ResponseWriter, r *http.Request) {
-	log.Printf("New connection: %#v", r)
+	logger := log.With().Str("remote_addr", r.RemoteAddr).Str("request_id", r.Header.Get("X-Request-Id")).Logger()
+	if atomic.LoadInt32(&cu.closed) != 0 {
+		response, _ := types.ServerResponse{
+			Status:  "service unavailable",
+			Message: "server_is_shutting_down",
+		}.MarshalJSON()
+		logger.Info().Msg("connection refused, server is shutting down")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write(response)
+		_ = r.Body.Close()
+		return
+	}
 	// Проверяет SessionId из cookie.
 	sessionID, err := r.Cookie("SessionId")
 	if err != nil {
@@ -58,6 +80,7 @@ func (cu *ConnectionUpgrader) HTTPEntryPoint(w http.ResponseWriter, r *http.Requ
 			Status:  "forbidden",
 			Message: "missing_sessionid_cookie",
 		}.MarshalJSON()
+		logger.Info().Msg("connection without SessionId cookie")
 		w.WriteHeader(http.StatusForbidden)
 		_, _ = w.Write(response)
 		_ = r.Body.Close()
@@ -71,6 +94,7 @@ func (cu *ConnectionUpgrader) HTTPEntryPoint(w http.ResponseWriter, r *http.Requ
 			Status:  "bad request",
 			Message: "error on upgrade connection: " + err.Error(),
 		}.MarshalJSON()
+		logger.Info().Err(err).Msg("error on upgrade connection")
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write(response)
 		_ = r.Body.Close()
@@ -83,8 +107,12 @@ func (cu *ConnectionUpgrader) HTTPEntryPoint(w http.ResponseWriter, r *http.Requ
 		Login:      login,
 		Avatar:     avatar,
 		Token:      sessionID.Value,
+		TokenHash:  user_connection.TokenHash(sessionID.Value),
 		Connection: WSConnection,
+		Codec:      types.CodecBySubprotocol(WSConnection.Subprotocol()),
 	}
+	logger.Info().Str("session", logging.Session(connection.Token)).Str("login", connection.Login).
+		Str("subprotocol", WSConnection.Subprotocol()).Msg("new connection")
 	cu.QueueToGame <- connection
 	return
 }
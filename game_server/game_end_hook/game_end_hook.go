@@ -0,0 +1,69 @@
+// Уведомление сервера авторизации о конце игры: одноразовые пользователи создаются
+// на одну партию, и сервер авторизации удаляет их, освобождая логины.
+
+package game_end_hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type Hook struct {
+	// адрес POST /api/v1/internal/game_ended сервера авторизации.
+	URL string
+	// общий с сервером авторизации --internal-token.
+	Token  string
+	Client *http.Client
+}
+
+func NewHook(url string, token string) (hook *Hook) {
+	hook = &Hook{
+		URL:    url,
+		Token:  token,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+	return
+}
+
+// не блокирует RoomsManager: запрос уходит в отдельной горутине.
+// Ошибка только записывается в лог, оставшихся пользователей удалит уборка по --disposable-user-ttl.
+func (h *Hook) GameEnded(tokenHash0 string, tokenHash1 string) {
+	go func() {
+		err := h.notify(tokenHash0, tokenHash1)
+		if err != nil {
+			log.Error().Err(err).Msg("error while reporting game end")
+		}
+	}()
+	return
+}
+
+func (h *Hook) notify(tokenHashes ...string) (err error) {
+	body, err := json.Marshal(struct {
+		SessionTokenHashes []string `json:"sessionTokenHashes"`
+	}{tokenHashes})
+	if err != nil {
+		return
+	}
+	request, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+h.Token)
+	response, err := h.Client.Do(request)
+	if err != nil {
+		err = errors.Wrap(err, "in http.Client.Do: ")
+		return
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		err = errors.New("authorization server answered " + strconv.Itoa(response.StatusCode))
+	}
+	return
+}
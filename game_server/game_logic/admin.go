@@ -0,0 +1,212 @@
+package game_logic
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/metrics"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/user_connection"
+)
+
+// Команды admin API. Состояние RoomsManager читается и меняется только в горутине
+// RoomsManager.Run, состояние комнаты - только в её GameMaster, поэтому HTTP обработчики
+// передают туда замыкания и ждут их выполнения.
+
+var ErrRoomNotFound = errors.New("room not found")
+var ErrSessionNotFound = errors.New("session not found")
+var ErrStopped = errors.New("rooms manager is stopped")
+
+// выполняет command в горутине RoomsManager.Run.
+// ErrStopped, если Run уже завершился и команда не выполнена.
+func (rm *RoomsManager) execute(command func()) (err error) {
+	done := make(chan struct{})
+	select {
+	case rm.AdminRequests <- func() {
+		command()
+		close(done)
+	}:
+	case <-rm.Stopped:
+		err = ErrStopped
+		return
+	}
+	// принятая Run команда выполняется до его завершения.
+	<-done
+	return
+}
+
+// выполняет command в горутине GameMaster комнаты. true из command заканчивает игру.
+// ok == false, если комната уже завершилась и команда не выполнена.
+func (r *Room) execute(command func() (gameOver bool)) (ok bool) {
+	done := make(chan struct{})
+	select {
+	case r.Commands <- func() (gameOver bool) {
+		gameOver = command()
+		close(done)
+		return
+	}:
+	case <-r.Done:
+		return
+	}
+	select {
+	case <-done:
+		ok = true
+	case <-r.Done:
+	}
+	return
+}
+
+func (rm *RoomsManager) room(roomId RoomId) (room *Room, err error) {
+	err = rm.execute(func() {
+		room = rm.Rooms[roomId]
+	})
+	return
+}
+
+func adminPlayer(role RoleId, user *user_connection.UserConnection) types.AdminPlayer {
+	return types.AdminPlayer{
+		Role:      int(role),
+		Login:     user.Login,
+		Session:   logging.SessionOfHash(user.TokenHash),
+		Connected: user.Connected(),
+	}
+}
+
+// список комнат и ждущий соперника игрок.
+func (rm *RoomsManager) AdminRooms() (rooms types.AdminRooms, err error) {
+	var roomList []*Room
+	err = rm.execute(func() {
+		rooms.Instance = rm.Config.InstanceID
+		if rm.WaitingConnection != nil {
+			waiting := adminPlayer(0, rm.WaitingConnection)
+			rooms.Waiting = &waiting
+		}
+		for _, room := range rm.Rooms {
+			roomList = append(roomList, room)
+		}
+	})
+	if err != nil {
+		return
+	}
+	// комнаты опрашиваются вне RoomsManager.Run: GameMaster при завершении сам пишет в RoomsManager.
+	rooms.Rooms = []types.AdminRoom{}
+	for _, room := range roomList {
+		var info types.AdminRoom
+		if room.execute(func() (gameOver bool) {
+			info = room.adminRoom()
+			return
+		}) {
+			rooms.Rooms = append(rooms.Rooms, info)
+		}
+	}
+	return
+}
+
+// ответственность: описание комнаты для admin API, вызывается только из GameMaster.
+func (r *Room) adminRoom() (info types.AdminRoom) {
+	info = types.AdminRoom{
+		Room:         uint(r.OwnNumber),
+		Phase:        "game",
+		CreatedAt:    r.CreatedAt.Format(time.RFC3339),
+		LastActivity: r.LastActivity.Format(time.RFC3339),
+		Players:      []types.AdminPlayer{adminPlayer(0, r.User0), adminPlayer(1, r.User1)},
+	}
+	if !r.User0UploadedCharacters || !r.User1UploadedCharacters {
+		info.Phase = "setup"
+	} else if r.WeaponReElection.WaitingForIt {
+		info.Phase = "re_election"
+	}
+	return
+}
+
+// карта комнаты в представлении сервера (игрок 0 сверху) и её описание.
+func (rm *RoomsManager) AdminDumpRoom(roomId RoomId) (info types.AdminRoom, dump string, err error) {
+	room, err := rm.room(roomId)
+	if err != nil {
+		return
+	}
+	if room == nil || !room.execute(func() (gameOver bool) {
+		info = room.adminRoom()
+		dump = room.Map.String()
+		return
+	}) {
+		err = ErrRoomNotFound
+	}
+	return
+}
+
+// принудительно заканчивает игру, winnerRole == nil - ничья.
+func (rm *RoomsManager) AdminEndRoom(roomId RoomId, winnerRole *RoleId) (err error) {
+	room, err := rm.room(roomId)
+	if err != nil {
+		return
+	}
+	if room == nil || !room.execute(func() (gameOver bool) {
+		room.Logger.Warn().Msg("room ended by admin")
+		room.TechnicalGameover(0, winnerRole, "admin")
+		room.TechnicalGameover(1, winnerRole, "admin")
+		gameOver = true
+		return
+	}) {
+		err = ErrRoomNotFound
+	}
+	return
+}
+
+// разрывает соединение сессии, session - идентификатор из AdminRooms.
+// Игрок в комнате может переподключиться, ждущий соперника игрок удаляется из очереди.
+func (rm *RoomsManager) AdminKick(session string) (err error) {
+	var room *Room
+	var role RoleId
+	stopErr := rm.execute(func() {
+		if rm.WaitingConnection != nil && logging.SessionOfHash(rm.WaitingConnection.TokenHash) == session {
+			connectionLogger(rm.WaitingConnection).Warn().Msg("kicked by admin")
+			kick(rm.WaitingConnection)
+			rm.WaitingConnection = nil
+			rm.setWaiting("")
+			metrics.WaitingPlayers.Set(0)
+			return
+		}
+		for tokenHash, game := range rm.ProcessedPlayers {
+			if logging.SessionOfHash(tokenHash) == session {
+				room, role = rm.Rooms[game.Room], game.Role
+				return
+			}
+		}
+		err = ErrSessionNotFound
+	})
+	if stopErr != nil {
+		err = stopErr
+		return
+	}
+	if room == nil {
+		return
+	}
+	// соединения игроков принадлежат GameMaster.
+	if !room.execute(func() (gameOver bool) {
+		user := room.User0
+		if role == 1 {
+			user = room.User1
+		}
+		// как и в swapConnection, читающая горутина комнаты получит ошибку и будет ждать переподключения.
+		if user.Connection != nil {
+			kick(user)
+		}
+		room.userLogger(role).Warn().Msg("kicked by admin")
+		return
+	}) {
+		err = ErrSessionNotFound
+	}
+	return
+}
+
+func kick(connection *user_connection.UserConnection) {
+	_ = connection.Connection.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "kicked by admin"),
+		time.Now().Add(time.Second))
+	_ = connection.Connection.Close()
+	return
+}
@@ -0,0 +1,56 @@
+package game_logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/user_connection"
+)
+
+func TestAdminAfterRunExited(t *testing.T) {
+	rm := NewRoomsManager(testConfig("a"), nil, NewMemoryRegistry())
+	connectionQueue := make(chan *user_connection.UserConnection)
+	close(connectionQueue)
+	rm.Run(connectionQueue)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := rm.AdminRooms()
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		if err != ErrStopped {
+			t.Fatalf("AdminRooms after Run: %v, want ErrStopped", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("AdminRooms blocked after Run exited")
+	}
+	if err := rm.AdminKick("1a2b3c4d"); err != ErrStopped {
+		t.Fatalf("AdminKick after Run: %v, want ErrStopped", err)
+	}
+}
+
+func TestAdminPlayerConnected(t *testing.T) {
+	connection, client, closeConnection := testConnection(t, "token0")
+	defer closeConnection()
+	room := newRoom(connection, &user_connection.UserConnection{TokenHash: "hash1"},
+		make(chan RoomId, 1), 1, testConfig("a"), nil)
+	go room.WebSocketReader(0, connection)
+	defer close(room.Recovery.User0IsAvailableRead)
+
+	if !adminPlayer(0, connection).Connected {
+		t.Fatal("open connection is not reported as connected")
+	}
+	if adminPlayer(1, room.User1).Connected {
+		t.Fatal("restored player without connection is reported as connected")
+	}
+	_ = client.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for adminPlayer(0, connection).Connected {
+		if time.Now().After(deadline) {
+			t.Fatal("closed connection is still reported as connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,49 @@
+package game_logic
+
+import (
+	"time"
+)
+
+// Поведение комнаты, если игрок не загрузил персонажей за Config.SetupTime.
+const (
+	// расставить персонажей за игрока случайным образом и начать игру.
+	SetupTimeoutGenerate = "generate"
+	// засчитать не успевшему игроку техническое поражение.
+	SetupTimeoutForfeit = "forfeit"
+)
+
+// Как разрешается бой с одинаковым оружием, если игроки не перевыбрали оружие
+// за Config.ReElectionTime или ничья повторилась больше Config.MaxConsecutiveTies раз.
+const (
+	// не успевшим игрокам назначается случайное оружие, гарантированно разное.
+	ReElectionRandom = "random"
+	// нападающий проигрывает, как будто оружие защищающегося сильнее.
+	ReElectionAttackerLoses = "attacker_loses"
+)
+
+// Настройки игровой логики, задаются из аргументов командной строки в main
+// и передаются RoomsManager -> Room при создании комнаты.
+type Config struct {
+	// время на расстановку персонажей с момента создания комнаты.
+	SetupTime time.Duration
+	// SetupTimeoutGenerate или SetupTimeoutForfeit.
+	SetupTimeoutPolicy string
+	// время на ответ "reassign_weapons" после "weapon_change_request".
+	ReElectionTime time.Duration
+	// ReElectionRandom или ReElectionAttackerLoses.
+	ReElectionPolicy string
+	// сколько раз подряд можно перевыбрать оружие в одном бою, 0 - без ограничения.
+	MaxConsecutiveTies int
+
+	// как часто WebSocketWriter отправляет клиенту "ping", должно быть меньше PongWait.
+	PingInterval time.Duration
+	// сколько ждать любого сообщения или "pong" от клиента, прежде чем считать соединение разорванным.
+	PongWait time.Duration
+	// время на отправку одного сообщения клиенту.
+	WriteWait time.Duration
+	// максимальный размер входящего сообщения в байтах, при превышении соединение закрывается.
+	MaxMessageSize int64
+	// имя этого экземпляра игрового сервера, уникальное среди всех экземпляров,
+	// доступного по адресу /game/v1/<InstanceID>/entrypoint.
+	InstanceID string
+}
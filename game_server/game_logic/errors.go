@@ -0,0 +1,33 @@
+package game_logic
+
+// Ошибка обработки запроса клиента с машиночитаемым кодом, который уходит
+// клиенту в поле "code" сообщения "error_message". Текст остаётся для отладки.
+type GameError struct {
+	Code string
+	Err  error
+}
+
+func (ge *GameError) Error() string {
+	return ge.Err.Error()
+}
+
+func withCode(code string, err error) error {
+	return &GameError{Code: code, Err: err}
+}
+
+// достаёт код из цепочки errors.Wrap, "bad_request", если код не назначен.
+func errorCode(err error) (code string) {
+	code = "bad_request"
+	for err != nil {
+		if gameError, ok := err.(*GameError); ok {
+			code = gameError.Code
+			return
+		}
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			return
+		}
+		err = causer.Cause()
+	}
+	return
+}
@@ -0,0 +1,48 @@
+package game_logic
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
+)
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+// Случайная, но заведомо правильная расстановка персонажей одного игрока:
+// ровно один флаг, остальные 13 персонажей поровну (насколько возможно)
+// вооружены камнем, ножницами и бумагой. Формат тот же, что и у "upload_map",
+// поэтому результат проходит ту же проверку, что и загруженная пользователем карта.
+func RandomUploadMap() (uploadMap types.UploadMap) {
+	weapons := [...]string{"rock", "scissors", "paper"}
+	uploadMap.Weapons[0] = "flag"
+	for i := 1; i < len(uploadMap.Weapons); i++ {
+		uploadMap.Weapons[i] = weapons[i%len(weapons)]
+	}
+	rand.Shuffle(len(uploadMap.Weapons), func(i, j int) {
+		uploadMap.Weapons[i], uploadMap.Weapons[j] = uploadMap.Weapons[j], uploadMap.Weapons[i]
+	})
+	return
+}
+
+// случайное оружие для перевыбора, отличное от оружия соперника, что бы бой точно разрешился.
+func randomWeaponExcept(rival Weapon) (weapon Weapon) {
+	weapons := make([]Weapon, 0, 2)
+	for _, w := range [...]Weapon{"rock", "scissors", "paper"} {
+		if w != rival {
+			weapons = append(weapons, w)
+		}
+	}
+	weapon = weapons[rand.Intn(len(weapons))]
+	return
+}
+
+// пара случайных различных оружий для перевыбора у обоих участников боя.
+func randomDistinctWeapons() (attacking Weapon, attacked Weapon) {
+	weapons := [...]Weapon{"rock", "scissors", "paper"}
+	attacking = weapons[rand.Intn(len(weapons))]
+	attacked = randomWeaponExcept(attacking)
+	return
+}
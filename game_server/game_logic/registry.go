@@ -0,0 +1,92 @@
+package game_logic
+
+import (
+	"sync"
+)
+
+// Расположение комнаты среди нескольких экземпляров игрового сервера.
+type RoomAddress struct {
+	InstanceID string
+	Room       RoomId
+}
+
+// Общий для всех экземпляров игрового сервера реестр: какой экземпляр владеет какой
+// комнатой, в какой комнате какая сессия и кто ждёт соперника. Соединения живут только
+// в процессе, который их принял, поэтому игрок, пришедший не туда, перенаправляется
+// на экземпляр, владеющий его комнатой или ждущим соперником.
+// Сессии хранятся как sha256 токена, см. UserConnection.TokenHash.
+// Вызывается только из горутины RoomsManager.Run каждого экземпляра.
+type Registry interface {
+	// запоминает комнату и сессии обоих игроков.
+	RegisterRoom(room RoomAddress, token0 string, token1 string) (err error)
+	// забывает комнату и сессии её игроков.
+	UnregisterRoom(room RoomAddress) (err error)
+	// комната, в которой играет сессия.
+	FindSession(token string) (room RoomAddress, found bool, err error)
+	// экземпляр, на котором ждёт соперника игрок с сессией token.
+	Waiting() (instanceID string, token string, found bool, err error)
+	// ставит ждущего соперника игрока, пустой token - никто не ждёт.
+	SetWaiting(instanceID string, token string) (err error)
+}
+
+// Реестр в памяти процесса, для единственного экземпляра сервера.
+type MemoryRegistry struct {
+	mutex    sync.Mutex
+	rooms    map[RoomAddress][2]string
+	sessions map[string]RoomAddress
+	waiting  struct {
+		instanceID string
+		token      string
+	}
+}
+
+func NewMemoryRegistry() (registry *MemoryRegistry) {
+	registry = &MemoryRegistry{
+		rooms:    make(map[RoomAddress][2]string),
+		sessions: make(map[string]RoomAddress),
+	}
+	return
+}
+
+func (mr *MemoryRegistry) RegisterRoom(room RoomAddress, token0 string, token1 string) (err error) {
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+	mr.rooms[room] = [2]string{token0, token1}
+	mr.sessions[token0] = room
+	mr.sessions[token1] = room
+	return
+}
+
+func (mr *MemoryRegistry) UnregisterRoom(room RoomAddress) (err error) {
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+	for _, token := range mr.rooms[room] {
+		if mr.sessions[token] == room {
+			delete(mr.sessions, token)
+		}
+	}
+	delete(mr.rooms, room)
+	return
+}
+
+func (mr *MemoryRegistry) FindSession(token string) (room RoomAddress, found bool, err error) {
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+	room, found = mr.sessions[token]
+	return
+}
+
+func (mr *MemoryRegistry) Waiting() (instanceID string, token string, found bool, err error) {
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+	instanceID, token = mr.waiting.instanceID, mr.waiting.token
+	found = token != ""
+	return
+}
+
+func (mr *MemoryRegistry) SetWaiting(instanceID string, token string) (err error) {
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+	mr.waiting.instanceID, mr.waiting.token = instanceID, token
+	return
+}
@@ -0,0 +1,158 @@
+package game_logic
+
+import (
+	"database/sql"
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// Реестр в PostgreSQL, общий для всех экземпляров игрового сервера.
+type PostgresRegistry struct {
+	db                *sql.DB
+	stmtInsertRoom    *sql.Stmt
+	stmtInsertSession *sql.Stmt
+	stmtDeleteRoom    *sql.Stmt
+	stmtSelectSession *sql.Stmt
+	stmtSelectWaiting *sql.Stmt
+	stmtUpdateWaiting *sql.Stmt
+}
+
+func NewPostgresRegistry(dataSourceName string) (registry *PostgresRegistry, err error) {
+	db, err := sql.Open("postgres", dataSourceName)
+	if err != nil {
+		err = errors.Wrap(err, "error on open connection to '"+dataSourceName+"'")
+		return
+	}
+	err = db.Ping()
+	if err != nil {
+		err = errors.Wrap(err, "error during the first connection to '"+dataSourceName+"': ")
+		return
+	}
+	registry = &PostgresRegistry{db: db}
+	err = registry.init()
+	return
+}
+
+func (pr *PostgresRegistry) init() (err error) {
+	//language=PostgreSQL
+	_, err = pr.db.Exec(`
+begin transaction;
+
+-- комнаты всех экземпляров игрового сервера.
+create table if not exists "game_room" (
+  "instance_id" text    not null,
+  "room_id"     integer not null,
+  primary key ("instance_id", "room_id")
+);
+
+-- сессии игроков, находящихся в комнатах, token - sha256 cookie "SessionId" в hex.
+create table if not exists "game_session" (
+  "token"       text    primary key,
+  "instance_id" text    not null,
+  "room_id"     integer not null,
+  foreign key ("instance_id", "room_id") references "game_room" on delete cascade
+);
+
+-- единственная строка: игрок, ждущий соперника, и экземпляр, держащий его соединение.
+create table if not exists "game_waiting" (
+  "id"          integer primary key check ("id" = 0),
+  "instance_id" text not null,
+  "token"       text not null
+);
+insert into "game_waiting" ("id", "instance_id", "token") values (0, '', '') on conflict do nothing;
+
+commit;
+	`)
+	if err != nil {
+		err = errors.Wrap(err, "error during preparation registry tables: ")
+		return
+	}
+	statements := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		//language=PostgreSQL
+		{&pr.stmtInsertRoom, `
+insert into "game_room" ("instance_id", "room_id") values ($1, $2) on conflict do nothing;`},
+		//language=PostgreSQL
+		{&pr.stmtInsertSession, `
+insert into "game_session" ("token", "instance_id", "room_id") values ($1, $2, $3)
+on conflict ("token") do update set "instance_id" = $2, "room_id" = $3;`},
+		//language=PostgreSQL
+		{&pr.stmtDeleteRoom, `
+delete from "game_room" where "instance_id" = $1 and "room_id" = $2;`},
+		//language=PostgreSQL
+		{&pr.stmtSelectSession, `
+select "instance_id", "room_id" from "game_session" where "token" = $1;`},
+		//language=PostgreSQL
+		{&pr.stmtSelectWaiting, `
+select "instance_id", "token" from "game_waiting" where "id" = 0;`},
+		//language=PostgreSQL
+		{&pr.stmtUpdateWaiting, `
+update "game_waiting" set "instance_id" = $1, "token" = $2 where "id" = 0;`},
+	}
+	for _, statement := range statements {
+		*statement.stmt, err = pr.db.Prepare(statement.query)
+		if err != nil {
+			err = errors.Wrap(err, "error on prepare registry statement: ")
+			return
+		}
+	}
+	return
+}
+
+func (pr *PostgresRegistry) RegisterRoom(room RoomAddress, token0 string, token1 string) (err error) {
+	_, err = pr.stmtInsertRoom.Exec(room.InstanceID, room.Room)
+	if err != nil {
+		err = errors.Wrap(err, "error on exec 'InsertRoom' statement: ")
+		return
+	}
+	for _, token := range [...]string{token0, token1} {
+		_, err = pr.stmtInsertSession.Exec(token, room.InstanceID, room.Room)
+		if err != nil {
+			err = errors.Wrap(err, "error on exec 'InsertSession' statement: ")
+			return
+		}
+	}
+	return
+}
+
+func (pr *PostgresRegistry) UnregisterRoom(room RoomAddress) (err error) {
+	_, err = pr.stmtDeleteRoom.Exec(room.InstanceID, room.Room)
+	if err != nil {
+		err = errors.Wrap(err, "error on exec 'DeleteRoom' statement: ")
+	}
+	return
+}
+
+func (pr *PostgresRegistry) FindSession(token string) (room RoomAddress, found bool, err error) {
+	err = pr.stmtSelectSession.QueryRow(token).Scan(&room.InstanceID, &room.Room)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = errors.Wrap(err, "error on exec 'SelectSession' statement: ")
+		return
+	}
+	found = true
+	return
+}
+
+func (pr *PostgresRegistry) Waiting() (instanceID string, token string, found bool, err error) {
+	err = pr.stmtSelectWaiting.QueryRow().Scan(&instanceID, &token)
+	if err != nil {
+		err = errors.Wrap(err, "error on exec 'SelectWaiting' statement: ")
+		return
+	}
+	found = token != ""
+	return
+}
+
+func (pr *PostgresRegistry) SetWaiting(instanceID string, token string) (err error) {
+	_, err = pr.stmtUpdateWaiting.Exec(instanceID, token)
+	if err != nil {
+		err = errors.Wrap(err, "error on exec 'UpdateWaiting' statement: ")
+	}
+	return
+}
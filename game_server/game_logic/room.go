@@ -1,17 +1,28 @@
 package game_logic
 
 import (
+	"errors"
 	"github.com/gorilla/websocket"
-	"log"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"strconv"
 	"time"
 
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/metrics"
 	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/user_connection"
 )
 
 const timeForMove = 5 * time.Minute
 
+// размер очереди исходящих сообщений одного игрока, при переполнении сообщения отбрасываются.
+const outgoingQueueSize = 32
+
+// Модель владения: все поля комнаты, кроме каналов, читает и изменяет только горутина GameMaster.
+// Читающие и пишущие горутины получают соединение аргументом и новые соединения через Recovery,
+// RoomsManager и admin API обращаются к комнате только через каналы.
 type Room struct {
-	// соединения с пользователями, могут подменятся во время игры
+	// соединения с пользователями, подменяются во время игры только в GameMaster.
 	User0 *user_connection.UserConnection // array index == RoleId
 	User1 *user_connection.UserConnection
 	// основные состояния игры.
@@ -34,6 +45,8 @@ type Room struct {
 		AttackingCharacter int
 		// атакуемый персонаж
 		AttackedCharacter int
+		// сколько раз подряд в этом бою выпало одинаковое оружие.
+		ConsecutiveTies int
 	}
 
 	// Каналы, c помощью которых go room.GameMaster() общается с
@@ -46,16 +59,24 @@ type Room struct {
 	}
 
 	// Каналы для синхронизации мастера игры и читающих/пишуших в Websocket горутин при разрыве соединения.
+	// Ёмкость 1, пишет только GameMaster, непрочитанное соединение вытесняется новым.
 	Recovery struct {
-		// приход сообщения означает:
+		// приход нового соединения означает:
 		// go room.WebSocketReader(0) может снова заблокироваться на чтение из сокета
-		User0IsAvailableRead chan struct{}
+		User0IsAvailableRead chan *user_connection.UserConnection
 		// go room.WebSocketWriter(0) может снова попытаться отправить сообщение пользователю User0
-		User0IsAvailableWrite chan struct{}
+		User0IsAvailableWrite chan *user_connection.UserConnection
 		// go room.WebSocketReader(1) может снова заблокироваться на чтение из сокета
-		User1IsAvailableRead chan struct{}
+		User1IsAvailableRead chan *user_connection.UserConnection
 		// go room.WebSocketWriter(1) может снова попытаться отправить сообщение пользователю User1
-		User1IsAvailableWrite chan struct{}
+		User1IsAvailableWrite chan *user_connection.UserConnection
+	}
+
+	// Reconnect передаёт сюда новое соединение игрока, GameMaster подменяет его и присылает состояние игры.
+	// Ёмкость 1, пишет только RoomsManager, непрочитанное соединение вытесняется и закрывается.
+	Reconnection struct {
+		User0 chan *user_connection.UserConnection
+		User1 chan *user_connection.UserConnection
 	}
 
 	// интервал ожидания бездействия игрока timeForMove = 5 минут.
@@ -63,12 +84,49 @@ type Room struct {
 	// если никто не сделал попытки что-то отправить - комната уничтожается.
 
 	TimeoutTimer *time.Timer
+	// время на расстановку персонажей Config.SetupTime, останавливается, когда оба загрузили карту.
+	SetupTimer *time.Timer
+	// время на перевыбор оружия Config.ReElectionTime, запускается при каждом "weapon_change_request".
+	ReElectionTimer *time.Timer
+	// время до принудительной остановки комнаты при остановке сервера, запускается из Drain.
+	DrainTimer *time.Timer
+	// RoomsManager передаёт сюда момент, к которому комната должна завершиться.
+	Drain chan time.Time
+	// настройки, общие для всех комнат.
+	Config Config
+	// куда комната сохраняет своё состояние, nil - не сохранять.
+	Storage Storage
 	// Что бы отрегистрировать комнату, надо отправить RoomId в канал:
 	Completed chan RoomId
+	// игра прервана остановкой сервера и продолжится после перезапуска.
+	// Пишет GameMaster до отправки в Completed, RoomsManager читает после.
+	Interrupted bool
 	OwnNumber RoomId
+	// момент создания комнаты, для метрики длительности игры.
+	CreatedAt time.Time
+	// время последнего сообщения от игроков.
+	LastActivity time.Time
+	// команды admin API, выполняются в GameMaster.
+	Commands chan func() (gameOver bool)
+	// закрывается при завершении GameMaster, после этого команды не выполняются.
+	Done chan struct{}
+	// логгер с полем "room", все записи комнаты идут через него.
+	Logger zerolog.Logger
+}
+
+func NewRoom(player0, player1 *user_connection.UserConnection, completedRooms chan RoomId, ownNumber RoomId, config Config, storage Storage) (room *Room) {
+	room = newRoom(player0, player1, completedRooms, ownNumber, config, storage)
+	// каналы буферизованы, сообщения уйдут, когда запустятся пишущие горутины.
+	room.SetupCountdown(0)
+	room.SetupCountdown(1)
+	room.start()
+	room.Logger.Info().Str("session0", logging.Session(room.User0.Token)).Str("login0", room.User0.Login).
+		Str("session1", logging.Session(room.User1.Token)).Str("login1", room.User1.Login).Msg("room created")
+	return
 }
 
-func NewRoom(player0, player1 *user_connection.UserConnection, completedRooms chan RoomId, ownNumber RoomId) (room *Room) {
+// создаёт комнату, не запуская горутин.
+func newRoom(player0, player1 *user_connection.UserConnection, completedRooms chan RoomId, ownNumber RoomId, config Config, storage Storage) (room *Room) {
 	room = &Room{
 		User0:        player0,
 		User1:        player1,
@@ -76,16 +134,36 @@ func NewRoom(player0, player1 *user_connection.UserConnection, completedRooms ch
 		Completed:    completedRooms,
 		OwnNumber:    ownNumber,
 		TimeoutTimer: time.NewTimer(timeForMove),
+		SetupTimer:   time.NewTimer(config.SetupTime),
+		Config:       config,
+		// создаются остановленными, запускаются только при перевыборе и при остановке сервера.
+		ReElectionTimer: time.NewTimer(config.ReElectionTime),
+		DrainTimer:      time.NewTimer(timeForMove),
+		Drain:           make(chan time.Time, 1),
+		Storage:         storage,
+		CreatedAt:       time.Now(),
+		LastActivity:    time.Now(),
+		Commands:        make(chan func() (gameOver bool)),
+		Done:            make(chan struct{}),
+		Logger:          log.With().Uint("room", uint(ownNumber)).Logger(),
 	}
+	room.ReElectionTimer.Stop()
+	room.DrainTimer.Stop()
 	room.Messaging.User0From = make(chan []byte, 5)
-	room.Messaging.User0To = make(chan []byte, 5)
+	room.Messaging.User0To = make(chan []byte, outgoingQueueSize)
 	room.Messaging.User1From = make(chan []byte, 5)
-	room.Messaging.User1To = make(chan []byte, 5)
-	room.Recovery.User0IsAvailableRead = make(chan struct{}, 1)
-	room.Recovery.User0IsAvailableWrite = make(chan struct{}, 1)
-	room.Recovery.User1IsAvailableRead = make(chan struct{}, 1)
-	room.Recovery.User1IsAvailableWrite = make(chan struct{}, 1)
+	room.Messaging.User1To = make(chan []byte, outgoingQueueSize)
+	room.Recovery.User0IsAvailableRead = make(chan *user_connection.UserConnection, 1)
+	room.Recovery.User0IsAvailableWrite = make(chan *user_connection.UserConnection, 1)
+	room.Recovery.User1IsAvailableRead = make(chan *user_connection.UserConnection, 1)
+	room.Recovery.User1IsAvailableWrite = make(chan *user_connection.UserConnection, 1)
+	room.Reconnection.User0 = make(chan *user_connection.UserConnection, 1)
+	room.Reconnection.User1 = make(chan *user_connection.UserConnection, 1)
+	return
+}
 
+// запускает обслуживающие комнату горутины.
+func (r *Room) start() {
 	// Внутри каждая комната обслуживается одним мастерм игры - горутиной.
 	// 4 горутины на комнату, что изолируют соединение от игровой логики и подметы соединений менеджером потерь.
 	//    ╭─User0From─▶─╮      ╭─◀─User1From─╮
@@ -94,22 +172,22 @@ func NewRoom(player0, player1 *user_connection.UserConnection, completedRooms ch
 	// 4 обслуживающие соединения горутины создаются в момент старта комнаты и живут, как и
 	// GameMaster всё время существования комнаты.
 	// func User0From обычно заблокирован на чтение из сокета, при разрыве соединения блокируется на
-	// чтение из User0IsAvailableRead. Если получает оттуда сигнал - пытается читать снова,
-	// если этот канал закрыт - завершает работу.
+	// чтение из User0IsAvailableRead. Если получает оттуда новое соединение - читает из него,
+	// если этот канал закрыт - завершает работу. Если GameMaster не успевает разбирать сообщения,
+	// User0From перестаёт читать сокет, пока в канале не освободится место.
 	// func User0To обычно заблокирован на чтение из канала User0To, если он взял данные от
 	// GameMaster, попытался отправить и не смог, то блокируется на чтение из User0IsAvailableWrite,
-	// Если получает оттуда сигнал - пытается отправить снова, если этот канал закрыт - завершает
-	// работу.
+	// Если получает оттуда новое соединение - пытается отправить снова, если этот канал закрыт -
+	// завершает работу. GameMaster никогда не ждёт пишущие горутины, смотри send.
 	// C timeout работает GameMaster: обновляет счётчик на каждое событие прихода данных.
 	// GameMaster содержит игровую логику, в один поток принимает/рассылает запросы, работает с
 	// картой, содержит JSPN RPC сервер, вызывающий функции объекта комнаты.
-	go room.WebSocketReader(0)
-	go room.WebSocketWriter(0)
-	go room.WebSocketReader(1)
-	go room.WebSocketWriter(1)
-	go room.GameMaster()
-
-	log.Printf("Room created with User0 = '%s', User1 = '%s'", room.User0.Token, room.User1.Token)
+	// соединения передаются аргументами до запуска GameMaster, дальше горутины не читают r.User0 и r.User1.
+	go r.WebSocketReader(0, r.User0)
+	go r.WebSocketWriter(0, r.User0)
+	go r.WebSocketReader(1, r.User1)
+	go r.WebSocketWriter(1, r.User1)
+	go r.GameMaster()
 	return
 }
 
@@ -135,7 +213,7 @@ func (r *Room) Stop() {
 	close(r.Messaging.User1To)
 	close(r.Recovery.User1IsAvailableWrite)
 
-	log.Print("room with User0.Token='" + r.User0.Token + "', r.User1.Token='" + r.User1.Token + "' closed")
+	r.Logger.Info().Msg("room closed")
 	return
 }
 
@@ -146,115 +224,244 @@ func (r *Room) Remove() {
 	return
 }
 
-// восстанавливает соединение и перезапускает
+// восстанавливает соединение, вызывается из RoomsManager.
+// Не блокируется: соединение подменит GameMaster, если он ещё не забрал предыдущее, оно закрывается.
 func (r *Room) Reconnect(user *user_connection.UserConnection, role RoleId) {
-	log.Printf("Reconnect sessioni = '%s' as role %d", user.Token, role)
+	r.Logger.Info().Str("role", role.String()).Str("session", logging.Session(user.Token)).Str("login", user.Login).
+		Msg("reconnect")
+	reconnection := r.Reconnection.User0
+	if role == 1 {
+		reconnection = r.Reconnection.User1
+	}
+	r.closeConnection(offer(reconnection, user))
+	return
+}
 
-	if role == 0 {
-		if r.User0 != nil {
-			_ = r.User0.Connection.Close()
-		}
-		r.User0 = user
-		// если в канале уже есть сигнал, пропускаем.
-		select {
-		case r.Recovery.User0IsAvailableRead <- struct{}{}:
-		default:
-		}
+// закрывает соединения, которые комната уже не заберёт. Вызывается из RoomsManager после завершения GameMaster.
+func (r *Room) dropReconnections() {
+	for _, reconnection := range []chan *user_connection.UserConnection{r.Reconnection.User0, r.Reconnection.User1} {
 		select {
-		case r.Recovery.User0IsAvailableWrite <- struct{}{}:
+		case user := <-reconnection:
+			r.closeConnection(user)
 		default:
 		}
+	}
+	return
+}
+
+// ответственность: подмена соединения игрока в GameMaster.
+// Старое соединение закрывается, что бы читающая и пишущая горутины перешли на новое.
+func (r *Room) swapConnection(role RoleId, user *user_connection.UserConnection) {
+	if role == 0 {
+		r.closeConnection(r.User0)
+		r.User0 = user
+		offer(r.Recovery.User0IsAvailableRead, user)
+		offer(r.Recovery.User0IsAvailableWrite, user)
 	} else {
-		if r.User1 != nil {
-			_ = r.User1.Connection.Close()
-		}
+		r.closeConnection(r.User1)
 		r.User1 = user
-		// если в канале уже есть сигнал, пропускаем.
+		offer(r.Recovery.User1IsAvailableRead, user)
+		offer(r.Recovery.User1IsAvailableWrite, user)
+	}
+	r.ResendState(role)
+	return
+}
+
+// кладёт соединение в канал ёмкостью 1, вытесняя непрочитанное.
+// Годится только для каналов с единственным отправителем, вытесненное соединение возвращается.
+func offer(channel chan *user_connection.UserConnection, user *user_connection.UserConnection) (displaced *user_connection.UserConnection) {
+	for {
 		select {
-		case r.Recovery.User1IsAvailableRead <- struct{}{}:
+		case channel <- user:
+			return
 		default:
 		}
 		select {
-		case r.Recovery.User1IsAvailableWrite <- struct{}{}:
+		case displaced = <-channel:
 		default:
 		}
 	}
+}
+
+// ставит сообщение в очередь пишущей горутины, не блокируя GameMaster.
+// Если очередь переполнена, игрок не успевает читать или давно отключён: сообщение отбрасывается,
+// соединение закрывается, после переподключения ResendState пришлёт состояние игры целиком.
+func (r *Room) send(role RoleId, message []byte) {
+	to, user := r.Messaging.User0To, r.User0
+	if role == 1 {
+		to, user = r.Messaging.User1To, r.User1
+	}
+	select {
+	case to <- message:
+	default:
+		metrics.DroppedMessages.Inc()
+		r.userLogger(role).Warn().Str("message", truncateForLog(message)).Msg("outgoing queue is full, message dropped")
+		r.closeConnection(user)
+	}
 	return
 }
 
-func (r *Room) WebSocketReader(role RoleId) {
-	// c.conn.SetReadLimit(maxMessageSize)
-	// c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	// c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
-	if role == 0 {
-		for {
-			_, message, err := r.User0.Connection.ReadMessage()
-			if err != nil {
-				log.Print("Error from user role 0 with Token '" + r.User0.Token + "': '" + err.Error() + "'.")
-				_, stillOpen := <-r.Recovery.User0IsAvailableRead
-				if !stillOpen {
-					close(r.Messaging.User0From)
-					break
-				}
-			} else {
-				log.Print("message from user role 0 with Token '" + r.User0.Token + "': '" + string(message) + "'.")
-				r.Messaging.User0From <- message
+func (r *Room) WebSocketReader(role RoleId, user *user_connection.UserConnection) {
+	metrics.RoomGoroutines.Inc()
+	defer metrics.RoomGoroutines.Dec()
+	from, available := r.Messaging.User0From, r.Recovery.User0IsAvailableRead
+	if role == 1 {
+		from, available = r.Messaging.User1From, r.Recovery.User1IsAvailableRead
+	}
+	logger := r.playerLogger(role, user)
+reading:
+	for {
+		message, err := r.readMessage(user)
+		if err != nil {
+			user.MarkLost()
+			logger.Info().Err(err).Msg("connection lost, waiting for reconnect")
+			newUser, stillOpen := <-available
+			if !stillOpen {
+				break
 			}
+			user = newUser
+			logger = r.playerLogger(role, user)
+			continue
 		}
-	} else {
-		for {
-			_, message, err := r.User1.Connection.ReadMessage()
-			if err != nil {
-				log.Print("Error from user role 1 with Token '" + r.User0.Token + "': '" + err.Error() + "'.")
-				_, stillOpen := <-r.Recovery.User1IsAvailableRead
-				if !stillOpen {
-					close(r.Messaging.User1From)
-					break
-				}
-			} else {
-				log.Print("message from user role 1 with Token '" + r.User0.Token + "': '" + string(message) + "'.")
-				r.Messaging.User1From <- message
-			}
+		if decoded, err := user.Codec.Decode(message); err == nil {
+			// нераскодированное сообщение GameMaster отвергнет как "invalid_message".
+			message = decoded
+		}
+		logger.Debug().Str("message", truncateForLog(message)).Msg("message received")
+		select {
+		case from <- message:
+		case <-r.Done:
+			break reading
 		}
 	}
-	log.Print("WebSocketReader room = " + r.OwnNumber.String() + ", role = " + role.String() + " correctly completed.")
+	r.Logger.Debug().Str("role", role.String()).Msg("WebSocketReader completed")
 	return
 }
 
-func (r *Room) WebSocketWriter(role RoleId) {
-	if role == 0 {
-	consistentMessageSending0:
-		for message := range r.Messaging.User0To {
-			for {
-				err := r.User0.Connection.WriteMessage(websocket.TextMessage, message)
-				if err != nil {
-					_, stillOpen := <-r.Recovery.User0IsAvailableWrite
-					if !stillOpen {
-						break consistentMessageSending0
-					}
-				} else {
-					break
-				}
+// читает следующее сообщение пользователя.
+// Ограничивает размер сообщения и сдвигает deadline чтения: соединение считается живым,
+// пока от клиента приходят сообщения или ответы "pong" на "ping" из WebSocketWriter.
+// Настройка повторяется перед каждым чтением, так как после Reconnect соединение новое.
+// При ошибке закрывает соединение, что бы и WebSocketWriter перешёл в ожидание переподключения.
+func (r *Room) readMessage(user *user_connection.UserConnection) (message []byte, err error) {
+	if user.Connection == nil {
+		// комната восстановлена из Storage, игрок ещё не переподключился.
+		err = errors.New("connection is not established yet")
+		return
+	}
+	user.Connection.SetReadLimit(r.Config.MaxMessageSize)
+	_ = user.Connection.SetReadDeadline(time.Now().Add(r.Config.PongWait))
+	user.Connection.SetPongHandler(func(string) error {
+		return user.Connection.SetReadDeadline(time.Now().Add(r.Config.PongWait))
+	})
+	_, message, err = user.Connection.ReadMessage()
+	if err != nil {
+		_ = user.Connection.Close()
+	}
+	return
+}
+
+func (r *Room) ping(user *user_connection.UserConnection) {
+	if user.Connection != nil {
+		_ = user.Connection.WriteControl(websocket.PingMessage, nil, time.Now().Add(r.Config.WriteWait))
+	}
+	return
+}
+
+func (r *Room) closeConnection(user *user_connection.UserConnection) {
+	if user != nil && user.Connection != nil {
+		_ = user.Connection.Close()
+	}
+	return
+}
+
+func (r *Room) WebSocketWriter(role RoleId, user *user_connection.UserConnection) {
+	metrics.RoomGoroutines.Inc()
+	defer metrics.RoomGoroutines.Dec()
+	to, available := r.Messaging.User0To, r.Recovery.User0IsAvailableWrite
+	if role == 1 {
+		to, available = r.Messaging.User1To, r.Recovery.User1IsAvailableWrite
+	}
+	// "ping" отправляется и во время ожидания переподключения: ошибка записи
+	// в мёртвое соединение ни на что не влияет, разрыв обнаружит WebSocketReader.
+	pingTicker := time.NewTicker(r.Config.PingInterval)
+	defer pingTicker.Stop()
+consistentMessageSending:
+	for {
+		select {
+		case message, ok := <-to:
+			if !ok {
+				break consistentMessageSending
 			}
-		}
-		_ = r.User0.Connection.Close()
-	} else {
-	consistentMessageSending1:
-		for message := range r.Messaging.User1To {
-			for {
-				err := r.User1.Connection.WriteMessage(websocket.TextMessage, message)
-				if err != nil {
-					_, stillOpen := <-r.Recovery.User1IsAvailableWrite
-					if !stillOpen {
-						break consistentMessageSending1
-					}
-				} else {
-					break
+			for r.writeMessage(user, message) != nil {
+				if available == nil {
+					break consistentMessageSending
 				}
+				newUser, stillOpen := <-available
+				if !stillOpen {
+					break consistentMessageSending
+				}
+				user = newUser
+			}
+		case newUser, stillOpen := <-available:
+			if !stillOpen {
+				// комната закрывается, дописываем оставшиеся в очереди сообщения.
+				available = nil
+				continue
 			}
+			user = newUser
+		case <-pingTicker.C:
+			r.ping(user)
 		}
-		_ = r.User1.Connection.Close()
 	}
-	log.Print("WebSocketWriter room = " + r.OwnNumber.String() + ", role = " + role.String() + " correctly completed.")
+	r.closeConnection(user)
+	r.Logger.Debug().Str("role", role.String()).Msg("WebSocketWriter completed")
+	return
+}
+
+// кодирует сообщение GameMaster в формат соединения пользователя и отправляет его.
+// Кодирование происходит при каждой попытке, так как после Reconnect у соединения может быть другой формат.
+func (r *Room) writeMessage(user *user_connection.UserConnection, message []byte) (err error) {
+	if user.Connection == nil {
+		err = errors.New("connection is not established yet")
+		return
+	}
+	data, err := user.Codec.Encode(message)
+	if err != nil {
+		return
+	}
+	messageType := websocket.TextMessage
+	if user.Codec.Binary() {
+		messageType = websocket.BinaryMessage
+	}
+	_ = user.Connection.SetWriteDeadline(time.Now().Add(r.Config.WriteWait))
+	err = user.Connection.WriteMessage(messageType, data)
+	return
+}
+
+// логгер для записей об одном из игроков комнаты, вызывается только из GameMaster.
+func (r *Room) userLogger(role RoleId) *zerolog.Logger {
+	user := r.User0
+	if role == 1 {
+		user = r.User1
+	}
+	return r.playerLogger(role, user)
+}
+
+func (r *Room) playerLogger(role RoleId, user *user_connection.UserConnection) *zerolog.Logger {
+	logger := r.Logger.With().Str("role", role.String()).Str("session", logging.Session(user.Token)).
+		Str("login", user.Login).Logger()
+	return &logger
+}
+
+// сообщения в лог пишутся не длиннее maxLoggedMessage байт.
+const maxLoggedMessage = 256
+
+func truncateForLog(message []byte) (truncated string) {
+	if len(message) <= maxLoggedMessage {
+		truncated = string(message)
+	} else {
+		truncated = string(message[:maxLoggedMessage]) + "... (" + strconv.Itoa(len(message)) + " bytes)"
+	}
 	return
 }
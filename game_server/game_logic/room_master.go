@@ -1,128 +1,170 @@
 package game_logic
 
 import (
-	"fmt"
 	"github.com/mailru/easyjson"
 	"github.com/pkg/errors"
-	"github.com/rs/zerolog/log"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/metrics"
 	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
 )
 
 func (r *Room) GameMaster() {
-	log.Printf("start GameMaster for room: %#v", *r)
+	metrics.RoomGoroutines.Inc()
+	defer metrics.RoomGoroutines.Dec()
+	defer close(r.Done)
+	r.Logger.Debug().Msg("GameMaster started")
 	var message []byte
 	var role RoleId
+	// сохраняем сразу, что бы игроки восстановленной после перезапуска комнаты нашли её.
+	r.persist()
 gameLoop:
 	for {
 		select {
 		case <-r.TimeoutTimer.C:
+			r.Logger.Info().Msg("room timed out")
+			r.Finish()
+			break gameLoop
+		case <-r.SetupTimer.C:
+			if r.SetupTimeout() {
+				r.Finish()
+				break gameLoop
+			}
+			r.persist()
+			continue
+		case <-r.ReElectionTimer.C:
+			if r.ReElectionTimeout() {
+				r.Finish()
+				break gameLoop
+			}
+			r.persist()
+			continue
+		case command := <-r.Commands:
+			if command() {
+				r.Finish()
+				break gameLoop
+			}
+			continue
+		case user := <-r.Reconnection.User0:
+			r.swapConnection(0, user)
+			continue
+		case user := <-r.Reconnection.User1:
+			r.swapConnection(1, user)
+			continue
+		case deadline := <-r.Drain:
+			r.ServerShutdown(0, deadline)
+			r.ServerShutdown(1, deadline)
+			r.DrainTimer.Reset(time.Until(deadline))
+			continue
+		case <-r.DrainTimer.C:
+			// игра не успела закончиться, её состояние уже в Storage, продолжится после перезапуска.
 			r.Stop()
+			r.Interrupted = true
 			r.Remove()
-			log.Printf("room = " + r.OwnNumber.String() + ", time out!")
+			r.Logger.Info().Msg("room interrupted by server shutdown")
 			break gameLoop
 		case message = <-r.Messaging.User0From:
 			role = 0
-			log.Printf("message came from the User0: " + string(message))
 		case message = <-r.Messaging.User1From:
 			role = 1
-			log.Printf("message came from the User1: " + string(message))
 		}
 		r.TimeoutTimer.Reset(timeForMove)
+		r.LastActivity = time.Now()
 
 		event := types.Event{}
 		err := event.UnmarshalJSON(message)
 		if err != nil {
-			response, _ := types.ErrorMessage("error while parsing first level: " + err.Error()).MarshalJSON()
-			response, _ = types.Event{
-				Method:    "error_message",
-				Parameter: response,
-			}.MarshalJSON()
-			if role == 0 {
-				r.Messaging.User0To <- response
-			} else {
-				r.Messaging.User1To <- response
-			}
-			continue
-		}
-		if event.Method == "upload_map" {
-			err := r.UploadMap(role, event.Parameter)
-			if err != nil {
-				response, _ := types.ErrorMessage("error while process 'upload_map': " + err.Error()).MarshalJSON()
-				response, _ = types.Event{
-					Method:    "error_message",
-					Parameter: response,
-				}.MarshalJSON()
-				if role == 0 {
-					r.Messaging.User0To <- response
-				} else {
-					r.Messaging.User1To <- response
-				}
-				if r.User0UploadedCharacters && r.User1UploadedCharacters {
-					r.DownloadMap(role)
-				}
-			}
+			r.ErrorMessage(role, "", "", withCode("invalid_message", errors.Wrap(err, "error while parsing first level: ")))
 			continue
 		}
-		if event.Method == "attempt_go_to_cell" {
-			gameover, err := r.AttemptGoToCell(role, event.Parameter)
-			if err != nil {
-				response, _ := types.ErrorMessage("error while process 'attempt_go_to_cell': " + err.Error()).MarshalJSON()
-				response, _ = types.Event{
-					Method:    "error_message",
-					Parameter: response,
-				}.MarshalJSON()
-				if role == 0 {
-					r.Messaging.User0To <- response
-				} else {
-					r.Messaging.User1To <- response
-				}
-				if r.User0UploadedCharacters && r.User1UploadedCharacters {
-					r.DownloadMap(role)
-				}
-			}
-			if gameover {
-				// к этому моменту эже все данные должны быть отправлены. только сетевые вопросы и остановка всех 5-и горутин.
-				r.Stop()
-				// отрегистирует в Rooms.
-				r.Remove()
-				break gameLoop
-			}
-			continue
+		metrics.Messages.WithLabelValues(methodLabel(event.Method)).Inc()
+		r.userLogger(role).Debug().Str("method", event.Method).Str("request_id", event.RequestID).Msg("call")
+		gameover, err := r.CallMethod(role, event)
+		if err != nil {
+			r.ErrorMessage(role, event.Method, event.RequestID, err)
+		} else if event.RequestID != "" {
+			r.Ack(role, event.Method, event.RequestID)
 		}
-		if event.Method == "reassign_weapons" {
-			err = r.ReassignWeapons(role, event.Parameter)
-			if err != nil {
-				response, _ := types.ErrorMessage("error while process 'reassign_weapons': " + err.Error()).MarshalJSON()
-				response, _ = types.Event{
-					Method:    "error_message",
-					Parameter: response,
-				}.MarshalJSON()
-				if role == 0 {
-					r.Messaging.User0To <- response
-				} else {
-					r.Messaging.User1To <- response
-				}
-				if r.User0UploadedCharacters && r.User1UploadedCharacters {
-					r.DownloadMap(role)
-				}
-			}
-			continue
+		if gameover {
+			r.Finish()
+			break gameLoop
 		}
-		// если ни один из трёх методов не отработал, прислали неверный метод, кидаем ошибку
-		response, _ := types.Event{
-			Method: "error_message",
-			Parameter: easyjson.RawMessage("unknown method '" + event.Method + "', " +
-				"available only ['attempt_go_to_cell', 'upload_map', 'reassign_weapons']."),
-		}.MarshalJSON()
-		if role == 0 {
-			r.Messaging.User0To <- response
-		} else {
-			r.Messaging.User1To <- response
+		r.persist()
+	}
+	r.Logger.Debug().Msg("GameMaster completed")
+	return
+}
+
+// ответственность: завершение законченной игры.
+func (r *Room) Finish() {
+	// к этому моменту эже все данные должны быть отправлены. только сетевые вопросы и остановка всех 5-и горутин.
+	metrics.GameDuration.Observe(time.Since(r.CreatedAt).Seconds())
+	r.Stop()
+	r.forget()
+	// отрегистирует в Rooms.
+	r.Remove()
+	return
+}
+
+// имя метода для меток метрик: неизвестные методы не должны порождать новых меток.
+func methodLabel(method string) (label string) {
+	if method == "" {
+		return
+	}
+	label = "unknown"
+	for _, name := range types.ClientMethodNames() {
+		if name == method {
+			label = method
 		}
 	}
-	log.Print("GameMaster for room = " + r.OwnNumber.String() + " correctly completed.")
+	return
+}
+
+// ответственность: по имени метода вызывает его обработчик.
+// Список методов должен совпадать с types.ClientMethods.
+func (r *Room) CallMethod(role RoleId, event types.Event) (gameOver bool, err error) {
+	switch event.Method {
+	case "hello":
+		err = r.Hello(role, event.Parameter)
+	case "upload_map":
+		err = r.UploadMap(role, event.Parameter)
+	case "upload_random_map":
+		err = r.UploadRandomMap(role)
+	case "attempt_go_to_cell":
+		gameOver, err = r.AttemptGoToCell(role, event.Parameter)
+	case "reassign_weapons":
+		gameOver, err = r.ReassignWeapons(role, event.Parameter)
+	default:
+		err = withCode("unknown_method", errors.New("unknown method '"+event.Method+"', "+
+			"available only ['"+strings.Join(types.ClientMethodNames(), "', '")+"']."))
+	}
+	return
+}
+
+// ответственность: отвечает версией протокола и списком методов сервера, не изменяет карту.
+// Ответ приходит в любом случае, ошибка означает, что клиент говорит на другой версии протокола.
+func (r *Room) Hello(role RoleId, message easyjson.RawMessage) (err error) {
+	var hello types.Hello
+	err = hello.UnmarshalJSON(message)
+	if err != nil {
+		err = withCode("invalid_parameter", errors.Wrap(err, "in json.Unmarshal message into types.Hello: "))
+		return
+	}
+	response, _ := types.HelloAnswer{
+		ProtocolVersion: types.ProtocolVersion,
+		Methods:         types.ClientMethodNames(),
+	}.MarshalJSON()
+	response, _ = types.Event{
+		Method:    "hello",
+		Parameter: response,
+	}.MarshalJSON()
+	r.send(role, response)
+	if hello.ProtocolVersion != types.ProtocolVersion {
+		err = withCode("unsupported_protocol_version", errors.New("unsupported protocol version "+strconv.Itoa(hello.ProtocolVersion)+
+			", server supports only "+strconv.Itoa(types.ProtocolVersion)))
+	}
 	return
 }
 
@@ -131,7 +173,7 @@ func (r *Room) UploadMap(role RoleId, message easyjson.RawMessage) (err error) {
 	var uploadedMap types.UploadMap
 	err = uploadedMap.UnmarshalJSON(message)
 	if err != nil {
-		err = errors.Wrap(err, "in json.Unmarshal message into types.UploadMap: ")
+		err = withCode("invalid_parameter", errors.Wrap(err, "in json.Unmarshal message into types.UploadMap: "))
 		return
 	}
 
@@ -140,7 +182,43 @@ func (r *Room) UploadMap(role RoleId, message easyjson.RawMessage) (err error) {
 		return
 	}
 
+	r.startGameIfUploaded()
+	return
+}
+
+// ответственность: расставляет персонажей пользователя случайным образом вместо него, начинает игру.
+// Пока соперник не загрузил карту, отсылает пользователю его собственную расстановку,
+// что бы клиенту не нужно было генерировать её самому.
+func (r *Room) UploadRandomMap(role RoleId) (err error) {
+	err = r.uploadMap(role, RandomUploadMap())
+	if err != nil {
+		return
+	}
+
+	if !r.User0UploadedCharacters || !r.User1UploadedCharacters {
+		r.DownloadMap(role)
+	}
+	r.startGameIfUploaded()
+	return
+}
+
+// останавливает таймер и выбрасывает значение, если он успел сработать до Stop:
+// иначе GameMaster прочитал бы его на следующем проходе цикла, уже после отмены.
+// Вызывается только из горутины GameMaster, которая единственная читает канал таймера.
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	return
+}
+
+// ответственность: если оба игрока загрузили персонажей, рассылает начальное состояние игры.
+func (r *Room) startGameIfUploaded() {
 	if r.User0UploadedCharacters && r.User1UploadedCharacters {
+		stopTimer(r.SetupTimer)
 		// Отсылает карту
 		r.DownloadMap(0)
 		r.DownloadMap(1)
@@ -154,6 +232,69 @@ func (r *Room) UploadMap(role RoleId, message easyjson.RawMessage) (err error) {
 	return
 }
 
+// ответственность: присылает переподключившемуся игроку текущее состояние игры, не изменяет карту.
+// Клиент мог потерять состояние вместе с соединением, а после перезапуска сервера - и вся комната
+// восстановлена из Storage.
+func (r *Room) ResendState(role RoleId) {
+	uploaded := r.User0UploadedCharacters
+	if role == 1 {
+		uploaded = r.User1UploadedCharacters
+	}
+	if !uploaded {
+		return
+	}
+	r.DownloadMap(role)
+	if !r.User0UploadedCharacters || !r.User1UploadedCharacters {
+		return
+	}
+	r.YourTurn(role)
+	reElection := &r.WeaponReElection
+	if reElection.WaitingForIt && !((role == 0 && reElection.User0ReElect) || (role == 1 && reElection.User1ReElect)) {
+		// нападающий персонаж принадлежит тому, чей ход.
+		if r.UserTurnNumber == role {
+			r.WeaponChangeRequest(role, reElection.AttackingCharacter)
+		} else {
+			r.WeaponChangeRequest(role, reElection.AttackedCharacter)
+		}
+	}
+	return
+}
+
+// ответственность: завершает фазу расстановки по истечении Config.SetupTime.
+// Согласно Config.SetupTimeoutPolicy расставляет персонажей за не успевших игроков
+// или засчитывает им техническое поражение (ничья, если не успели оба).
+func (r *Room) SetupTimeout() (gameOver bool) {
+	// игра уже началась: таймер сработал одновременно с последней загрузкой карты.
+	if r.User0UploadedCharacters && r.User1UploadedCharacters {
+		return
+	}
+	r.Logger.Info().Str("policy", r.Config.SetupTimeoutPolicy).Msg("setup timeout")
+	if r.Config.SetupTimeoutPolicy == SetupTimeoutForfeit {
+		var winner *RoleId
+		if r.User0UploadedCharacters != r.User1UploadedCharacters {
+			winner = new(RoleId)
+			if r.User1UploadedCharacters {
+				*winner = 1
+			}
+		}
+		r.TechnicalGameover(0, winner, "setup_timeout")
+		r.TechnicalGameover(1, winner, "setup_timeout")
+		gameOver = true
+		return
+	}
+	for _, role := range [...]RoleId{0, 1} {
+		if (role == 0 && r.User0UploadedCharacters) || (role == 1 && r.User1UploadedCharacters) {
+			continue
+		}
+		if err := r.uploadMap(role, RandomUploadMap()); err != nil {
+			// случайная карта всегда правильная, сюда попасть нельзя.
+			r.userLogger(role).Error().Err(err).Msg("random map rejected")
+		}
+	}
+	r.startGameIfUploaded()
+	return
+}
+
 func (r *Room) uploadMap(role RoleId, uploadedMap types.UploadMap) (err error) {
 	if role == 0 {
 		if !r.User0UploadedCharacters {
@@ -164,7 +305,7 @@ func (r *Room) uploadMap(role RoleId, uploadedMap types.UploadMap) (err error) {
 				var weapon Weapon
 				weapon, err = NewWeapon(uploadedMap.Weapons[i])
 				if err != nil {
-					err = errors.Wrap(err, "in NewWeapon: ")
+					err = withCode("unknown_weapon", errors.Wrap(err, "in NewWeapon: "))
 					return
 				}
 				if weapon == "flag" {
@@ -176,13 +317,13 @@ func (r *Room) uploadMap(role RoleId, uploadedMap types.UploadMap) (err error) {
 				}
 			}
 			if numberOfFlags != 1 {
-				err = errors.New("map must contain exactly one flag, but " +
-					strconv.Itoa(numberOfFlags) + " found")
+				err = withCode("wrong_number_of_flags", errors.New("map must contain exactly one flag, but "+
+					strconv.Itoa(numberOfFlags)+" found"))
 				return
 			}
 			r.User0UploadedCharacters = true
 		} else {
-			err = errors.New("characters already loaded")
+			err = withCode("characters_already_loaded", errors.New("characters already loaded"))
 			return
 		}
 	} else {
@@ -194,7 +335,7 @@ func (r *Room) uploadMap(role RoleId, uploadedMap types.UploadMap) (err error) {
 				var weapon Weapon
 				weapon, err = NewWeapon(uploadedMap.Weapons[i])
 				if err != nil {
-					err = errors.Wrap(err, "in NewWeapon: ")
+					err = withCode("unknown_weapon", errors.Wrap(err, "in NewWeapon: "))
 					return
 				}
 				if weapon == "flag" {
@@ -206,13 +347,13 @@ func (r *Room) uploadMap(role RoleId, uploadedMap types.UploadMap) (err error) {
 				}
 			}
 			if numberOfFlags != 1 {
-				err = errors.New("map must contain exactly one flag, but " +
-					strconv.Itoa(int(numberOfFlags)) + " found")
+				err = withCode("wrong_number_of_flags", errors.New("map must contain exactly one flag, but "+
+					strconv.Itoa(int(numberOfFlags))+" found"))
 				return
 			}
 			r.User1UploadedCharacters = true
 		} else {
-			err = errors.New("characters already loaded")
+			err = withCode("characters_already_loaded", errors.New("characters already loaded"))
 			return
 		}
 	}
@@ -244,11 +385,7 @@ func (r *Room) DownloadMap(role RoleId) {
 		Method:    "download_map",
 		Parameter: parameter,
 	}.MarshalJSON()
-	if role == 0 {
-		r.Messaging.User0To <- response
-	} else {
-		r.Messaging.User1To <- response
-	}
+	r.send(role, response)
 	return
 }
 
@@ -260,14 +397,14 @@ func (r *Room) YourRival(role RoleId) {
 			Method:    "your_rival",
 			Parameter: []byte(response),
 		}.MarshalJSON()
-		r.Messaging.User1To <- response
+		r.send(1, response)
 	} else {
 		response, _ := types.YourRival(r.User0.Login).MarshalJSON()
 		response, _ = types.Event{
 			Method:    "your_rival",
 			Parameter: []byte(response),
 		}.MarshalJSON()
-		r.Messaging.User0To <- response
+		r.send(0, response)
 	}
 	return
 }
@@ -284,11 +421,7 @@ func (r *Room) YourTurn(role RoleId) {
 		Method:    "your_turn",
 		Parameter: response,
 	}.MarshalJSON()
-	if role == 0 {
-		r.Messaging.User0To <- response
-	} else {
-		r.Messaging.User1To <- response
-	}
+	r.send(role, response)
 	return
 }
 
@@ -299,12 +432,12 @@ func (r *Room) AttemptGoToCell(role RoleId, message easyjson.RawMessage) (gameOv
 	var attemptGoToCell types.AttemptGoToCell
 	err = attemptGoToCell.UnmarshalJSON(message)
 	if err != nil {
-		err = errors.Wrap(err, "in json.Unmarshal message into types.attemptGoToCell: ")
+		err = withCode("invalid_parameter", errors.Wrap(err, "in json.Unmarshal message into types.attemptGoToCell: "))
 		return
 	}
 	err = attemptGoToCell.Check()
 	if err != nil {
-		err = errors.Wrap(err, "invalid coordinates: ")
+		err = withCode("invalid_coordinates", errors.Wrap(err, "invalid coordinates: "))
 		return
 	}
 	if role == 0 {
@@ -320,23 +453,23 @@ func (r *Room) AttemptGoToCellLogic(role RoleId, from int, to int) (gameOver boo
 	// не было спора про перевыбор оружия в данный момент неоконченного
 	// и был ход этого игрока.
 	if r.UserTurnNumber != role {
-		err = errors.New("it's not your turn now")
+		err = withCode("not_your_turn", errors.New("it's not your turn now"))
 		return
 	}
 	if !r.User0UploadedCharacters || !r.User1UploadedCharacters {
-		err = errors.New("The map is not loaded yet. Wait for it.")
+		err = withCode("map_not_loaded", errors.New("The map is not loaded yet. Wait for it."))
 		return
 	}
 	if r.WeaponReElection.WaitingForIt {
-		err = errors.New("At that moment you still need to reassign the weapon.")
+		err = withCode("re_election_in_progress", errors.New("At that moment you still need to reassign the weapon."))
 		return
 	}
 	if r.Map[from] == nil {
-		err = errors.New("there is no character at " + strconv.Itoa(from))
+		err = withCode("no_character", errors.New("there is no character at "+strconv.Itoa(from)))
 		return
 	}
 	if r.Map[from].Role != role {
-		err = errors.New("this is not your character at " + strconv.Itoa(from))
+		err = withCode("not_your_character", errors.New("this is not your character at "+strconv.Itoa(from)))
 		return
 	}
 	// Тут точно существующий персонаж, принадлежащий игроку.
@@ -357,12 +490,12 @@ func (r *Room) AttemptGoToCellLogic(role RoleId, from int, to int) (gameOver boo
 	}
 	// если в целевой клетке ты
 	if r.Map[to].Role == role {
-		err = errors.New("attempt to attack yourself")
+		err = withCode("attack_yourself", errors.New("attempt to attack yourself"))
 		return
 	}
 	// проверяем, нет ли там флага
 	if r.Map[to].Weapon == "flag" {
-		log.Print("game over in room = " + r.OwnNumber.String())
+		r.userLogger(role).Info().Msg("game over, flag captured")
 		r.Gameover(0, role, from, to)
 		r.Gameover(1, role, from, to)
 		gameOver = true
@@ -370,52 +503,25 @@ func (r *Room) AttemptGoToCellLogic(role RoleId, from int, to int) (gameOver boo
 	}
 	// проверяем победу над обычным оружием.
 	if r.Map[from].Weapon.IsExceed(r.Map[to].Weapon) {
-		winnerWeapon := r.Map[from].Weapon
-		loserWeapon := r.Map[to].Weapon
-		// двигаем персонажа
-		r.Map[to] = r.Map[from]
-		r.Map[from] = nil
-		// ставим, что оружие победителя спалилось.
-		r.Map[to].ShowedWeapon = true
-		// меняем ход
-		if r.UserTurnNumber == 0 {
-			r.UserTurnNumber = 1
-		} else {
-			r.UserTurnNumber = 0
-		}
-		// отсылаем изменения.
-		r.Attack(0, from, winnerWeapon, to, loserWeapon)
-		r.Attack(1, from, winnerWeapon, to, loserWeapon)
-		// отсылаем смену хода
-		r.YourTurn(0)
-		r.YourTurn(1)
+		r.attackerWins(from, to)
 		return
 	}
 	// проверяем поражение
 	if r.Map[to].Weapon.IsExceed(r.Map[from].Weapon) {
-		winnerWeapon := r.Map[to].Weapon
-		loserWeapon := r.Map[from].Weapon
-		// убираем проигравшего нападавшего персонажа, победитель передвигается на клетку проигравшего.
-		r.Map[from] = nil
-		// ставим, что оружие победителя спалилось.
-		r.Map[to].ShowedWeapon = true
-		// меняем ход
-		if r.UserTurnNumber == 0 {
-			r.UserTurnNumber = 1
-		} else {
-			r.UserTurnNumber = 0
-		}
-		// отсылаем изменения.
-		r.Attack(0, to, winnerWeapon, from, loserWeapon)
-		r.Attack(1, to, winnerWeapon, from, loserWeapon)
-		// отсылаем смену хода
-		r.YourTurn(0)
-		r.YourTurn(1)
+		r.attackerLoses(from, to)
 		return
 	}
 	// проверяем, что одинаковое оружие
 	if r.Map[to].Weapon == r.Map[from].Weapon {
-		fmt.Print(r.Map)
+		r.Logger.Debug().Str("map", r.Map.String()).Msg("tie")
+
+		r.WeaponReElection.ConsecutiveTies++
+		if r.Config.MaxConsecutiveTies != 0 && r.WeaponReElection.ConsecutiveTies > r.Config.MaxConsecutiveTies {
+			// игроки раз за разом выбирают одно и то же, разрешаем бой без них.
+			r.Logger.Info().Str("policy", r.Config.ReElectionPolicy).Msg("too many consecutive ties")
+			gameOver, err = r.resolveTie(from, to, true, true)
+			return
+		}
 
 		// запускаем процедуру перевыбора.
 		r.WeaponReElection.WaitingForIt = true
@@ -423,6 +529,9 @@ func (r *Room) AttemptGoToCellLogic(role RoleId, from int, to int) (gameOver boo
 		r.WeaponReElection.User1ReElect = false
 		r.WeaponReElection.AttackingCharacter = from
 		r.WeaponReElection.AttackedCharacter = to
+		// прошлый перевыбор мог закончиться одновременно со срабатыванием таймера.
+		stopTimer(r.ReElectionTimer)
+		r.ReElectionTimer.Reset(r.Config.ReElectionTime)
 
 		// просим игроков перевыбрать оружие для своего персонажа, ход не меняется.
 		if r.UserTurnNumber == 0 {
@@ -437,27 +546,134 @@ func (r *Room) AttemptGoToCellLogic(role RoleId, from int, to int) (gameOver boo
 	return
 }
 
+// ответственность: нападающий победил: занимает клетку проигравшего, ход переходит сопернику.
+// Изменяет карту и отсылает изменения обоим игрокам.
+func (r *Room) attackerWins(from int, to int) {
+	winnerWeapon := r.Map[from].Weapon
+	loserWeapon := r.Map[to].Weapon
+	// двигаем персонажа
+	r.Map[to] = r.Map[from]
+	r.Map[from] = nil
+	// ставим, что оружие победителя спалилось.
+	r.Map[to].ShowedWeapon = true
+	// бой разрешился, счётчик ничьих начинается заново.
+	r.WeaponReElection.ConsecutiveTies = 0
+	// меняем ход
+	if r.UserTurnNumber == 0 {
+		r.UserTurnNumber = 1
+	} else {
+		r.UserTurnNumber = 0
+	}
+	// отсылаем изменения.
+	r.Attack(0, from, winnerWeapon, to, loserWeapon)
+	r.Attack(1, from, winnerWeapon, to, loserWeapon)
+	// отсылаем смену хода
+	r.YourTurn(0)
+	r.YourTurn(1)
+	return
+}
+
+// ответственность: нападающий проиграл и убран с карты, ход переходит сопернику.
+// Изменяет карту и отсылает изменения обоим игрокам.
+func (r *Room) attackerLoses(from int, to int) {
+	winnerWeapon := r.Map[to].Weapon
+	loserWeapon := r.Map[from].Weapon
+	// убираем проигравшего нападавшего персонажа, победитель передвигается на клетку проигравшего.
+	r.Map[from] = nil
+	// ставим, что оружие победителя спалилось.
+	r.Map[to].ShowedWeapon = true
+	// бой разрешился, счётчик ничьих начинается заново.
+	r.WeaponReElection.ConsecutiveTies = 0
+	// меняем ход
+	if r.UserTurnNumber == 0 {
+		r.UserTurnNumber = 1
+	} else {
+		r.UserTurnNumber = 0
+	}
+	// отсылаем изменения.
+	r.Attack(0, to, winnerWeapon, from, loserWeapon)
+	r.Attack(1, to, winnerWeapon, from, loserWeapon)
+	// отсылаем смену хода
+	r.YourTurn(0)
+	r.YourTurn(1)
+	return
+}
+
+// ответственность: разрешает бой одинакового оружия без участия игроков согласно Config.ReElectionPolicy.
+// attackingRandom, attackedRandom - каким персонажам можно назначить случайное оружие
+// (тем, чьи игроки не успели перевыбрать). Хотя бы один из них должен быть true.
+func (r *Room) resolveTie(from int, to int, attackingRandom bool, attackedRandom bool) (gameOver bool, err error) {
+	r.WeaponReElection.WaitingForIt = false
+	stopTimer(r.ReElectionTimer)
+	if r.Config.ReElectionPolicy == ReElectionAttackerLoses {
+		r.attackerLoses(from, to)
+		return
+	}
+	switch {
+	case attackingRandom && attackedRandom:
+		r.Map[from].Weapon, r.Map[to].Weapon = randomDistinctWeapons()
+	case attackingRandom:
+		r.Map[from].Weapon = randomWeaponExcept(r.Map[to].Weapon)
+	default:
+		r.Map[to].Weapon = randomWeaponExcept(r.Map[from].Weapon)
+	}
+	// оружие теперь точно разное, бой разрешится.
+	gameOver, err = r.AttemptGoToCellLogic(r.UserTurnNumber, from, to)
+	return
+}
+
+// ответственность: игроки не успели перевыбрать оружие за Config.ReElectionTime,
+// бой разрешается согласно Config.ReElectionPolicy.
+func (r *Room) ReElectionTimeout() (gameOver bool) {
+	if !r.WeaponReElection.WaitingForIt {
+		return
+	}
+	r.Logger.Info().Str("policy", r.Config.ReElectionPolicy).Msg("re-election timeout")
+	// нападает всегда тот, чей сейчас ход.
+	attackingReElect, attackedReElect := r.WeaponReElection.User0ReElect, r.WeaponReElection.User1ReElect
+	if r.UserTurnNumber == 1 {
+		attackingReElect, attackedReElect = attackedReElect, attackingReElect
+	}
+	gameOver, err := r.resolveTie(r.WeaponReElection.AttackingCharacter, r.WeaponReElection.AttackedCharacter,
+		!attackingReElect, !attackedReElect)
+	if err != nil {
+		r.InternalError(err)
+		gameOver = true
+	}
+	return
+}
+
+// ответственность: аварийно завершает игру ничьей, если состояние комнаты оказалось
+// неконсистентным. Сервер продолжает работать, вызывающий должен остановить комнату.
+func (r *Room) InternalError(err error) {
+	r.Logger.Error().Err(err).Str("map", r.Map.String()).Msg("internal error")
+	r.TechnicalGameover(0, nil, "internal_error")
+	r.TechnicalGameover(1, nil, "internal_error")
+	return
+}
+
 // ответственность: проводит загружает перевыбранное оружие,
 // вызывает AttemptGoToCell снова, как бужто перевыбора небыло.
-func (r *Room) ReassignWeapons(role RoleId, message easyjson.RawMessage) (err error) {
+// gameOver == true, если бой завершил игру или комната сломалась и её надо остановить.
+func (r *Room) ReassignWeapons(role RoleId, message easyjson.RawMessage) (gameOver bool, err error) {
 	reassignWeapons := types.ReassignWeapons{}
 	err = reassignWeapons.UnmarshalJSON(message)
 	if err != nil {
-		err = errors.Wrap(err, "parsing error: ")
+		err = withCode("invalid_parameter", errors.Wrap(err, "parsing error: "))
 		return
 	}
 	weapon, err := NewWeapon(reassignWeapons.NewWeapon)
 	if err != nil {
-		err = errors.Wrap(err, "incorrect weapon: ")
+		err = withCode("unknown_weapon", errors.Wrap(err, "incorrect weapon: "))
 		return
 	}
 	if weapon == "flag" {
-		err = errors.New("'flag' cannot be assigned during re-election.")
+		err = withCode("flag_re_election", errors.New("'flag' cannot be assigned during re-election."))
 		return
 	}
 	// загрузка произойдёт, если сервер ждёт её, и этот игрок ещё не загрузил ничего.
 	if !r.WeaponReElection.WaitingForIt {
-		err = errors.New("there is no requirement to re-select a weapon at the moment.")
+		err = withCode("no_re_election", errors.New("there is no requirement to re-select a weapon at the moment."))
 		return
 	}
 	if role == 0 {
@@ -470,7 +686,7 @@ func (r *Room) ReassignWeapons(role RoleId, message easyjson.RawMessage) (err er
 			}
 			r.WeaponReElection.User0ReElect = true
 		} else {
-			err = errors.New("You have already downloaded the re-selection.")
+			err = withCode("already_re_elected", errors.New("You have already downloaded the re-selection."))
 			return
 		}
 	} else {
@@ -482,18 +698,20 @@ func (r *Room) ReassignWeapons(role RoleId, message easyjson.RawMessage) (err er
 			}
 			r.WeaponReElection.User1ReElect = true
 		} else {
-			err = errors.New("You have already downloaded the re-selection.")
+			err = withCode("already_re_elected", errors.New("You have already downloaded the re-selection."))
 			return
 		}
 	}
 	if r.WeaponReElection.User0ReElect && r.WeaponReElection.User1ReElect {
 		// то мы как будто бы проводим ход снова, как будто бы небыло перевыбора.
+		stopTimer(r.ReElectionTimer)
 		r.WeaponReElection.WaitingForIt = false
-		_, err = r.AttemptGoToCellLogic(r.UserTurnNumber, r.WeaponReElection.AttackingCharacter, r.WeaponReElection.AttackedCharacter)
+		gameOver, err = r.AttemptGoToCellLogic(r.UserTurnNumber, r.WeaponReElection.AttackingCharacter, r.WeaponReElection.AttackedCharacter)
 		if err != nil {
 			// Тут точно не должно быть ошибки, которую можно обработать кодом.
-			fmt.Print(r.Map)
-			panic(err)
+			r.InternalError(err)
+			gameOver = true
+			err = nil
 		}
 	}
 	return
@@ -514,11 +732,7 @@ func (r *Room) MoveCharacter(role RoleId, from int, to int) {
 		Method:    "move_character",
 		Parameter: response,
 	}.MarshalJSON()
-	if role == 0 {
-		r.Messaging.User0To <- response
-	} else {
-		r.Messaging.User1To <- response
-	}
+	r.send(role, response)
 	return
 }
 
@@ -544,11 +758,7 @@ func (r *Room) Attack(role RoleId, winner int, winnerWeapon Weapon, loser int, l
 		Method:    "attack",
 		Parameter: response,
 	}.MarshalJSON()
-	if role == 0 {
-		r.Messaging.User0To <- response
-	} else {
-		r.Messaging.User1To <- response
-	}
+	r.send(role, response)
 	return
 }
 
@@ -567,11 +777,7 @@ func (r *Room) AddWeapon(role RoleId, coordinates int, weapon Weapon) {
 		Method:    "add_weapon",
 		Parameter: response,
 	}.MarshalJSON()
-	if role == 0 {
-		r.Messaging.User0To <- response
-	} else {
-		r.Messaging.User1To <- response
-	}
+	r.send(role, response)
 	return
 }
 
@@ -588,11 +794,92 @@ func (r *Room) WeaponChangeRequest(role RoleId, characterOfPlayer int) {
 		Method:    "weapon_change_request",
 		Parameter: response,
 	}.MarshalJSON()
-	if role == 0 {
-		r.Messaging.User0To <- response
-	} else {
-		r.Messaging.User1To <- response
+	r.send(role, response)
+	return
+}
+
+// ответственность: отправляет время, оставшееся на расстановку персонажей, не изменяет карту.
+func (r *Room) SetupCountdown(role RoleId) {
+	response, _ := types.SetupCountdown{
+		Seconds:   int(r.Config.SetupTime / time.Second),
+		OnTimeout: r.Config.SetupTimeoutPolicy,
+	}.MarshalJSON()
+	response, _ = types.Event{
+		Method:    "setup_countdown",
+		Parameter: response,
+	}.MarshalJSON()
+	r.send(role, response)
+	return
+}
+
+// ответственность: предупреждение о скорой остановке сервера, не изменяет карту.
+func (r *Room) ServerShutdown(role RoleId, deadline time.Time) {
+	response, _ := types.ServerShutdown{
+		Seconds: int(time.Until(deadline) / time.Second),
+	}.MarshalJSON()
+	response, _ = types.Event{
+		Method:    "server_shutdown",
+		Parameter: response,
+	}.MarshalJSON()
+	r.send(role, response)
+	return
+}
+
+// ответственность: сборка конца игры без взятия флага, не изменяет карту и не прекращает игру.
+// winnerRole == nil означает ничью.
+func (r *Room) TechnicalGameover(role RoleId, winnerRole *RoleId, reason string) {
+	technicalGameOver := types.TechnicalGameOver{
+		Reason: reason,
 	}
+	if winnerRole != nil {
+		winner := *winnerRole == role
+		technicalGameOver.Winner = &winner
+	}
+	response, _ := technicalGameOver.MarshalJSON()
+	response, _ = types.Event{
+		Method:    "technical_gameover",
+		Parameter: response,
+	}.MarshalJSON()
+	r.send(role, response)
+	return
+}
+
+// ответственность: отправка сообщения об ошибке клиенту, не изменяет карту и состояния.
+// method и requestID - из вызвавшего ошибку запроса, пустые, если запрос не разобран.
+// Следом за ним отправляет "download_map", если карта загружена, потому что
+// ошибка чаще всего означает рассогласование состояния клиента и сервера.
+func (r *Room) ErrorMessage(role RoleId, method string, requestID string, err error) {
+	metrics.Errors.WithLabelValues(methodLabel(method), errorCode(err)).Inc()
+	r.userLogger(role).Warn().Err(err).Str("method", method).Str("request_id", requestID).
+		Str("code", errorCode(err)).Msg("error_message sent")
+	response, _ := types.ErrorMessage{
+		Code:    errorCode(err),
+		Message: err.Error(),
+		Method:  method,
+	}.MarshalJSON()
+	response, _ = types.Event{
+		Method:    "error_message",
+		RequestID: requestID,
+		Parameter: response,
+	}.MarshalJSON()
+	r.send(role, response)
+	if r.User0UploadedCharacters && r.User1UploadedCharacters {
+		r.DownloadMap(role)
+	}
+	return
+}
+
+// ответственность: подтверждение успешной обработки запроса с request_id, не изменяет карту.
+func (r *Room) Ack(role RoleId, method string, requestID string) {
+	response, _ := types.Ack{
+		Method: method,
+	}.MarshalJSON()
+	response, _ = types.Event{
+		Method:    "ack",
+		RequestID: requestID,
+		Parameter: response,
+	}.MarshalJSON()
+	r.send(role, response)
 	return
 }
 
@@ -612,11 +899,7 @@ func (r *Room) Gameover(role RoleId, winnerRole RoleId, from int, to int) {
 		Method:    "gameover",
 		Parameter: response,
 	}.MarshalJSON()
-	if role == 0 {
-		r.Messaging.User0To <- response
-	} else {
-		r.Messaging.User1To <- response
-	}
+	r.send(role, response)
 	return
 }
 
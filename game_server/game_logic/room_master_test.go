@@ -0,0 +1,83 @@
+package game_logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/user_connection"
+)
+
+// комната с уже расставленными персонажами: камень игрока 0 на клетке 14 напротив камня игрока 1 на 21.
+// Горутины не запускаются, тест сам играет роль GameMaster.
+func tieRoom(config Config) (room *Room) {
+	room = newRoom(&user_connection.UserConnection{}, &user_connection.UserConnection{},
+		make(chan RoomId, 1), 1, config, nil)
+	room.Map[14] = &Сharacter{Role: 0, Weapon: "rock"}
+	room.Map[21] = &Сharacter{Role: 1, Weapon: "rock"}
+	room.User0UploadedCharacters = true
+	room.User1UploadedCharacters = true
+	stopTimer(room.SetupTimer)
+	return
+}
+
+// выбрасывает исходящие сообщения, что бы очереди не переполнились.
+func dropOutgoing(room *Room) {
+	for _, to := range []chan []byte{room.Messaging.User0To, room.Messaging.User1To} {
+		for len(to) > 0 {
+			<-to
+		}
+	}
+	return
+}
+
+func reassign(t *testing.T, room *Room, role RoleId, weapon string) (gameOver bool) {
+	message, _ := types.ReassignWeapons{NewWeapon: weapon}.MarshalJSON()
+	gameOver, err := room.ReassignWeapons(role, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropOutgoing(room)
+	return
+}
+
+// Перевыбор завершился одновременно со срабатыванием таймера, и тут же снова ничья.
+// Новый перевыбор должен ждать Config.ReElectionTime, а не разрешиться сразу старым срабатыванием.
+// Старое срабатывание остаётся в канале таймера до Go 1.23, в Dockerfile - golang:1.11;
+// новые версии Go сами выбрасывают его при Stop и Reset.
+func TestReElectionTimerAfterConsecutiveTies(t *testing.T) {
+	config := testConfig("a")
+	config.ReElectionTime = 50 * time.Millisecond
+	room := tieRoom(config)
+
+	if _, err := room.AttemptGoToCellLogic(0, 14, 21); err != nil {
+		t.Fatal(err)
+	}
+	dropOutgoing(room)
+	if !room.WeaponReElection.WaitingForIt {
+		t.Fatal("tie does not start re-election")
+	}
+	// таймер сработал, но GameMaster ещё не успел его прочитать: обрабатывал перевыбор.
+	time.Sleep(2 * config.ReElectionTime)
+	reassign(t, room, 0, "paper")
+	reassign(t, room, 1, "paper")
+	if !room.WeaponReElection.WaitingForIt || room.WeaponReElection.ConsecutiveTies != 2 {
+		t.Fatalf("second tie is not waiting for re-election: %+v", room.WeaponReElection)
+	}
+
+	secondTie := time.Now()
+	select {
+	case <-room.ReElectionTimer.C:
+		if elapsed := time.Since(secondTie); elapsed < config.ReElectionTime {
+			t.Fatalf("re-election timer fired after %v, want at least %v", elapsed, config.ReElectionTime)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("re-election timer is not running")
+	}
+	if room.ReElectionTimeout() {
+		t.Fatal("tie resolution ended the game")
+	}
+	if room.WeaponReElection.WaitingForIt {
+		t.Fatal("re-election is not resolved by timeout")
+	}
+}
@@ -0,0 +1,250 @@
+package game_logic
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/user_connection"
+)
+
+// Стресс-тест гонок комнаты: переподключения и подмена соединений в GameMaster идут
+// одновременно с переполнением исходящей очереди, концом игры и остановкой сервера.
+// Запускать с -race. Тест падает, если после завершения комнаты остались горутины или
+// незакрытые соединения, если пишущая горутина вернулась к уже подменённому соединению,
+// или если запись в соединение пришла после того, как комната его закрыла и завершилась.
+
+// серверная сторона WebSocket соединения, отмечает записи и закрытие.
+type trackedConn struct {
+	net.Conn
+	tracker *connTracker
+	// роль и номер соединения роли, задаются до передачи соединения в комнату.
+	role   RoleId
+	number int
+	closed bool
+}
+
+func (tc *trackedConn) Write(data []byte) (int, error) {
+	tc.tracker.written(tc)
+	return tc.Conn.Write(data)
+}
+
+func (tc *trackedConn) Close() error {
+	tc.tracker.mutex.Lock()
+	tc.closed = true
+	tc.tracker.mutex.Unlock()
+	return tc.Conn.Close()
+}
+
+type connTracker struct {
+	mutex sync.Mutex
+	conns []*trackedConn
+	// номер последнего соединения роли, в которое писала комната.
+	lastWritten [2]int
+	// комната завершилась, все её горутины вышли.
+	finished   bool
+	violations []string
+}
+
+func (ct *connTracker) written(tc *trackedConn) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+	switch {
+	case ct.finished:
+		ct.violations = append(ct.violations, fmt.Sprintf("role %d connection %d: write after room completed", tc.role, tc.number))
+	case tc.closed && tc.number < ct.lastWritten[tc.role]:
+		ct.violations = append(ct.violations, fmt.Sprintf("role %d: write to closed connection %d after connection %d",
+			tc.role, tc.number, ct.lastWritten[tc.role]))
+	case tc.number > ct.lastWritten[tc.role]:
+		ct.lastWritten[tc.role] = tc.number
+	}
+	return
+}
+
+type trackingListener struct {
+	net.Listener
+	tracker *connTracker
+}
+
+func (tl trackingListener) Accept() (net.Conn, error) {
+	conn, err := tl.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	tracked := &trackedConn{Conn: conn, tracker: tl.tracker}
+	tl.tracker.mutex.Lock()
+	tl.tracker.conns = append(tl.tracker.conns, tracked)
+	tl.tracker.mutex.Unlock()
+	return tracked, nil
+}
+
+// сервер, выдающий соединения игроков. Клиенты читают всё, что пришло, пока сервер не закроет соединение.
+type stressServer struct {
+	t        *testing.T
+	server   *httptest.Server
+	tracker  *connTracker
+	accepted chan *websocket.Conn
+	numbers  [2]int
+	clients  sync.WaitGroup
+}
+
+func newStressServer(t *testing.T) (ss *stressServer) {
+	ss = &stressServer{t: t, tracker: &connTracker{}, accepted: make(chan *websocket.Conn, 1)}
+	ss.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ss.accepted <- conn
+	}))
+	ss.server.Listener = trackingListener{Listener: ss.server.Listener, tracker: ss.tracker}
+	ss.server.Start()
+	return
+}
+
+// новое соединение игрока role, вызывается из одной горутины на роль.
+func (ss *stressServer) connect(role RoleId) (connection *user_connection.UserConnection) {
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ss.server.URL, "http"), nil)
+	if err != nil {
+		ss.t.Fatal(err)
+	}
+	conn := <-ss.accepted
+	tracked := conn.UnderlyingConn().(*trackedConn)
+	ss.numbers[role]++
+	tracked.role, tracked.number = role, ss.numbers[role]
+	ss.clients.Add(1)
+	go func() {
+		defer ss.clients.Done()
+		defer client.Close()
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	connection = &user_connection.UserConnection{
+		Token:      "token" + role.String(),
+		TokenHash:  user_connection.TokenHash("token" + role.String()),
+		Connection: conn,
+		Codec:      types.JSONCodec{},
+	}
+	return
+}
+
+func TestRoomReconnectStress(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	defer zerolog.SetGlobalLevel(level)
+
+	for iteration := 0; iteration < 20; iteration++ {
+		byDrain := iteration%2 == 1
+		if !t.Run(fmt.Sprintf("iteration_%d_drain_%v", iteration, byDrain), func(t *testing.T) {
+			stressRoom(t, byDrain)
+		}) {
+			break
+		}
+	}
+}
+
+func stressRoom(t *testing.T, byDrain bool) {
+	ss := newStressServer(t)
+	defer ss.server.Close()
+	baseline := runtime.NumGoroutine()
+
+	config := testConfig("a")
+	config.PingInterval = time.Millisecond
+	completed := make(chan RoomId, 1)
+	room := NewRoom(ss.connect(0), ss.connect(1), completed, 1, config, nil)
+
+	var workers sync.WaitGroup
+	// переподключения обоих игроков, как их передаёт RoomsManager.
+	reconnected := make(chan struct{})
+	go func() {
+		defer close(reconnected)
+		for i := 0; i < 10; i++ {
+			role := RoleId(i % 2)
+			room.Reconnect(ss.connect(role), role)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	// переполнение исходящих очередей: send закрывает соединение и отбрасывает сообщения.
+	// Очереди переполняются то у одного, то у другого игрока, остальные соединения подменяются открытыми.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		message := []byte(`{"method":"stress","parameter":"` + strings.Repeat("x", 256) + `"}`)
+		for burst := 0; room.execute(func() (gameOver bool) {
+			for i := 0; i < outgoingQueueSize+burst%8; i++ {
+				room.send(RoleId(burst%2), message)
+			}
+			return
+		}); burst++ {
+			time.Sleep(time.Duration(burst%4) * time.Millisecond)
+		}
+	}()
+
+	<-reconnected
+	time.Sleep(5 * time.Millisecond)
+	if byDrain {
+		room.Drain <- time.Now().Add(5 * time.Millisecond)
+	} else {
+		room.execute(func() (gameOver bool) {
+			room.TechnicalGameover(0, nil, "admin")
+			room.TechnicalGameover(1, nil, "admin")
+			gameOver = true
+			return
+		})
+	}
+	select {
+	case <-completed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("room is not completed")
+	}
+	workers.Wait()
+	// как RoomsManager.processRoomRemoval.
+	room.dropReconnections()
+
+	waitForGoroutines(t, baseline)
+	ss.tracker.mutex.Lock()
+	ss.tracker.finished = true
+	ss.tracker.mutex.Unlock()
+	ss.clients.Wait()
+
+	ss.tracker.mutex.Lock()
+	defer ss.tracker.mutex.Unlock()
+	for _, violation := range ss.tracker.violations {
+		t.Error(violation)
+	}
+	for _, conn := range ss.tracker.conns {
+		if !conn.closed {
+			t.Errorf("role %d connection %d is left open", conn.role, conn.number)
+		}
+	}
+}
+
+// ждёт, пока завершатся горутины комнаты, иначе печатает оставшиеся.
+func waitForGoroutines(t *testing.T, baseline int) {
+	deadline := time.Now().Add(10 * time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			stacks := bytes.Buffer{}
+			_ = pprof.Lookup("goroutine").WriteTo(&stacks, 1)
+			t.Fatalf("%d goroutines leaked:\n%s", runtime.NumGoroutine()-baseline, stacks.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return
+}
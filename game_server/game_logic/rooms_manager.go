@@ -3,9 +3,16 @@ package game_logic
 import (
 	"errors"
 	"fmt"
-	"log"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"strconv"
+	"time"
 
+	"github.com/gorilla/websocket"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/metrics"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
 	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/user_connection"
 )
 
@@ -131,7 +138,7 @@ type RoomsManager struct {
 	// Список соединений, существующих в данный момент.
 	// используется для повторного подключения к той же игре, что и раньше.
 	// изменяется из конструктора/деструктора игровой комнаты.
-	// Ключ - login пользователя.
+	// Ключ - sha256 токена сессии, см. UserConnection.TokenHash.
 	ProcessedPlayers map[string]GameToConnect
 	// Игровые комнаты.
 	Rooms map[RoomId]*Room
@@ -142,18 +149,92 @@ type RoomsManager struct {
 	// канал "требование удаления"
 	// комната передаёт сюда собственный RoomId, и комната, оба соединения удаляется из RoomManager.
 	CompletedRooms chan RoomId
+	// настройки, передаваемые каждой новой комнате.
+	Config Config
+	// хранилище состояний комнат, nil - игры не переживают перезапуск.
+	Storage Storage
+	// общий с другими экземплярами сервера реестр комнат, сессий и ждущего игрока.
+	Registry Registry
+	// канал "требование остановки": момент, к которому все комнаты должны завершиться.
+	DrainRequest chan time.Time
+	// сервер останавливается, новые комнаты не создаются.
+	Draining bool
+	// закрывается, когда при остановке сервера не осталось ни одной комнаты.
+	Drained chan struct{}
+	// команды admin API, выполняются в Run.
+	AdminRequests chan func()
+	// закрывается при выходе из Run, после этого команды admin API не выполняются.
+	Stopped chan struct{}
+	// вызывается, когда игра закончилась, с хешами сессий игроков. nil - никого не уведомлять.
+	GameEnded func(tokenHash0 string, tokenHash1 string)
 }
 
-func NewRoomsManager() (roomsManager *RoomsManager) {
+func NewRoomsManager(config Config, storage Storage, registry Registry) (roomsManager *RoomsManager) {
 	roomsManager = &RoomsManager{
 		ProcessedPlayers: make(map[string]GameToConnect),
 		Rooms:            make(map[RoomId]*Room),
 		CompletedRooms:   make(chan RoomId, 5),
+		Config:           config,
+		Storage:          storage,
+		Registry:         registry,
+		DrainRequest:     make(chan time.Time, 1),
+		Drained:          make(chan struct{}),
+		AdminRequests:    make(chan func()),
+		Stopped:          make(chan struct{}),
 	}
 	return
 }
 
+// Восстанавливает комнаты, сохранённые в Storage до перезапуска сервера.
+// Вызывается из main до Run.
+func (rm *RoomsManager) RestoreRooms() (err error) {
+	if rm.Storage == nil {
+		return
+	}
+	snapshots, err := rm.Storage.LoadAll()
+	if err != nil {
+		return
+	}
+	// сначала все комнаты регистрируются, потом запускаются: ошибка реестра не должна оставить
+	// запущенные комнаты, о которых RoomsManager не знает.
+	for i, snapshot := range snapshots {
+		err = rm.Registry.RegisterRoom(RoomAddress{rm.Config.InstanceID, snapshot.OwnNumber},
+			snapshot.User0TokenHash, snapshot.User1TokenHash)
+		if err != nil {
+			for _, registered := range snapshots[:i] {
+				_ = rm.Registry.UnregisterRoom(RoomAddress{rm.Config.InstanceID, registered.OwnNumber})
+			}
+			return
+		}
+	}
+	for _, snapshot := range snapshots {
+		rm.Rooms[snapshot.OwnNumber] = RestoreRoom(snapshot, rm.CompletedRooms, rm.Config, rm.Storage)
+		rm.ProcessedPlayers[snapshot.User0TokenHash] = GameToConnect{
+			Room: snapshot.OwnNumber,
+			Role: 0,
+		}
+		rm.ProcessedPlayers[snapshot.User1TokenHash] = GameToConnect{
+			Room: snapshot.OwnNumber,
+			Role: 1,
+		}
+		metrics.ActiveRooms.Inc()
+		if snapshot.OwnNumber >= rm.RoomNumber {
+			rm.RoomNumber = snapshot.OwnNumber + 1
+		}
+	}
+	log.Info().Int("rooms", len(snapshots)).Msg("rooms restored")
+	return
+}
+
+// Начинает остановку: комнаты получат deadline, новые игры не начнутся.
+// Вызывается из main, дождаться завершения можно чтением из rm.Drained.
+func (rm *RoomsManager) Drain(deadline time.Time) {
+	rm.DrainRequest <- deadline
+	return
+}
+
 func (rm *RoomsManager) Run(connectionQueue chan *user_connection.UserConnection) {
+	defer close(rm.Stopped)
 	for connectionQueue != nil && rm.CompletedRooms != nil {
 		select { // https://stackoverflow.com/questions/13666253/breaking-out-of-a-select-statement-when-all-channels-are-closed
 		case RoomId, ok := <-rm.CompletedRooms:
@@ -162,6 +243,10 @@ func (rm *RoomsManager) Run(connectionQueue chan *user_connection.UserConnection
 			} else {
 				rm.CompletedRooms = nil
 			}
+		case deadline := <-rm.DrainRequest:
+			rm.processDrain(deadline)
+		case request := <-rm.AdminRequests:
+			request()
 		case connection, ok := <-connectionQueue:
 			if ok {
 				rm.processUserAddition(connection)
@@ -169,54 +254,206 @@ func (rm *RoomsManager) Run(connectionQueue chan *user_connection.UserConnection
 				connectionQueue = nil
 			}
 		}
+		if rm.Draining && len(rm.Rooms) == 0 {
+			close(rm.Drained)
+			break
+		}
 	}
 	return
 }
 
+func (rm *RoomsManager) processDrain(deadline time.Time) {
+	log.Info().Int("rooms", len(rm.Rooms)).Time("deadline", deadline).Msg("drain rooms")
+	rm.Draining = true
+	if rm.WaitingConnection != nil {
+		rm.rejectConnection(rm.WaitingConnection)
+		rm.WaitingConnection = nil
+		rm.setWaiting("")
+		metrics.WaitingPlayers.Set(0)
+	}
+	for _, room := range rm.Rooms {
+		room.Drain <- deadline
+	}
+	return
+}
+
+// закрывает соединение, для которого не будет создана комната.
+func (rm *RoomsManager) rejectConnection(connection *user_connection.UserConnection) {
+	connectionLogger(connection).Info().Msg("connection rejected, server is shutting down")
+	_ = connection.Connection.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server is shutting down"),
+		time.Now().Add(time.Second))
+	_ = connection.Connection.Close()
+	return
+}
+
 func (rm *RoomsManager) processUserAddition(connection *user_connection.UserConnection) {
 	// если пользователь с таким cookie sessionid уже играет
-	game, ok := rm.ProcessedPlayers[connection.Token]
-	if ok {
-		// то восстанавливаем соединение.
-		log.Printf("Reconnect user = '%s' in role %d to room %d", connection.Token, game.Role, game.Room)
-		rm.Rooms[game.Room].Reconnect(connection, game.Role)
+	if game, ok := rm.ProcessedPlayers[connection.TokenHash]; ok {
+		if room, exists := rm.Rooms[game.Room]; exists {
+			// то восстанавливаем соединение.
+			room.Reconnect(connection, game.Role)
+			metrics.Reconnects.Inc()
+			return
+		}
+		// комната уже удалена, а сессия осталась: игрок начнёт новую игру.
+		connectionLogger(connection).Error().Uint("room", uint(game.Room)).Msg("session refers to non-existing room")
+		delete(rm.ProcessedPlayers, connection.TokenHash)
+	}
+
+	if rm.redirectToOwner(connection) {
+		return
+	}
+
+	if rm.Draining {
+		rm.rejectConnection(connection)
 		return
 	}
 
 	if rm.WaitingConnection == nil {
-		log.Printf("Set connection user = '%s' as waiting", connection.Token)
+		if rm.redirectToWaiting(connection) {
+			return
+		}
+		connectionLogger(connection).Info().Msg("waiting for a rival")
 		rm.WaitingConnection = connection
+		rm.setWaiting(connection.TokenHash)
+		metrics.WaitingPlayers.Set(1)
 		return
 	}
 
 	// добавление в новую комнату 2-х соединений и регистрация пользователей,
 	// как находящихся в процессе игры.
-	log.Printf("create room %d user0 = '%s', user1 = '%s'", rm.RoomNumber, rm.WaitingConnection.Token, connection.Token)
 
-	rm.Rooms[rm.RoomNumber] = NewRoom(rm.WaitingConnection, connection, rm.CompletedRooms, rm.RoomNumber)
-	rm.ProcessedPlayers[rm.WaitingConnection.Token] = GameToConnect{
+	rm.Rooms[rm.RoomNumber] = NewRoom(rm.WaitingConnection, connection, rm.CompletedRooms, rm.RoomNumber, rm.Config, rm.Storage)
+	rm.ProcessedPlayers[rm.WaitingConnection.TokenHash] = GameToConnect{
 		Room: rm.RoomNumber,
 		Role: 0,
 	}
-	rm.ProcessedPlayers[connection.Token] = GameToConnect{
+	rm.ProcessedPlayers[connection.TokenHash] = GameToConnect{
 		Room: rm.RoomNumber,
 		Role: 1,
 	}
+	err := rm.Registry.RegisterRoom(RoomAddress{rm.Config.InstanceID, rm.RoomNumber},
+		rm.WaitingConnection.TokenHash, connection.TokenHash)
+	if err != nil {
+		log.Error().Err(err).Uint("room", uint(rm.RoomNumber)).Msg("error while registering room")
+	}
 	rm.WaitingConnection = nil
+	rm.setWaiting("")
+	metrics.WaitingPlayers.Set(0)
+	metrics.ActiveRooms.Inc()
 	rm.RoomNumber++
 	return
 }
 
+// логгер для записей о ещё не попавшем в комнату соединении.
+func connectionLogger(connection *user_connection.UserConnection) *zerolog.Logger {
+	logger := log.With().Str("session", logging.Session(connection.Token)).Str("login", connection.Login).Logger()
+	return &logger
+}
+
+// если игра пользователя идёт на другом экземпляре сервера, перенаправляет его туда.
+func (rm *RoomsManager) redirectToOwner(connection *user_connection.UserConnection) (redirected bool) {
+	room, found, err := rm.Registry.FindSession(connection.TokenHash)
+	if err != nil {
+		connectionLogger(connection).Error().Err(err).Msg("error while searching session in registry")
+		return
+	}
+	if !found {
+		return
+	}
+	if room.InstanceID == rm.Config.InstanceID {
+		// комнаты уже нет, например, сервер перезапущен без Storage.
+		err = rm.Registry.UnregisterRoom(room)
+		if err != nil {
+			log.Error().Err(err).Uint("room", uint(room.Room)).Msg("error while unregistering stale room")
+		}
+		return
+	}
+	rm.redirect(connection, room.InstanceID)
+	redirected = true
+	return
+}
+
+// если соперника ждёт другой экземпляр сервера, перенаправляет пользователя туда,
+// так как соединения обоих игроков комнаты должны быть в одном процессе.
+func (rm *RoomsManager) redirectToWaiting(connection *user_connection.UserConnection) (redirected bool) {
+	instanceID, tokenHash, found, err := rm.Registry.Waiting()
+	if err != nil {
+		log.Error().Err(err).Msg("error while reading waiting player from registry")
+		return
+	}
+	if !found || instanceID == rm.Config.InstanceID || tokenHash == connection.TokenHash {
+		return
+	}
+	rm.redirect(connection, instanceID)
+	redirected = true
+	return
+}
+
+func (rm *RoomsManager) setWaiting(tokenHash string) {
+	instanceID := rm.Config.InstanceID
+	if tokenHash == "" {
+		instanceID = ""
+	}
+	err := rm.Registry.SetWaiting(instanceID, tokenHash)
+	if err != nil {
+		log.Error().Err(err).Msg("error while setting waiting player in registry")
+	}
+	return
+}
+
+// отправляет "redirect" на экземпляр instanceID и закрывает соединение.
+func (rm *RoomsManager) redirect(connection *user_connection.UserConnection, instanceID string) {
+	connectionLogger(connection).Info().Str("instance", instanceID).Msg("redirect")
+	metrics.Redirects.Inc()
+	response, _ := types.Redirect{
+		Instance: instanceID,
+		URL:      "/game/v1/" + instanceID + "/entrypoint",
+	}.MarshalJSON()
+	response, _ = types.Event{
+		Method:    "redirect",
+		Parameter: response,
+	}.MarshalJSON()
+	data, err := connection.Codec.Encode(response)
+	if err == nil {
+		messageType := websocket.TextMessage
+		if connection.Codec.Binary() {
+			messageType = websocket.BinaryMessage
+		}
+		_ = connection.Connection.SetWriteDeadline(time.Now().Add(rm.Config.WriteWait))
+		_ = connection.Connection.WriteMessage(messageType, data)
+	}
+	_ = connection.Connection.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "redirect"),
+		time.Now().Add(time.Second))
+	_ = connection.Connection.Close()
+	return
+}
+
 func (rm *RoomsManager) processRoomRemoval(roomId RoomId) (err error) {
 	room, ok := rm.Rooms[roomId]
 	if !ok {
 		err = errors.New("attempt to delete non-existing room by RoomId = " + roomId.String())
-		log.Print(err)
+		log.Error().Uint("room", uint(roomId)).Msg("attempt to delete non-existing room")
 		return
 	}
-	delete(rm.ProcessedPlayers, room.User0.Token)
-	delete(rm.ProcessedPlayers, room.User1.Token)
+	// прерванная остановкой сервера комната сохранена в Storage и будет восстановлена этим же экземпляром,
+	// закончившиеся игры отрегистрируются и при остановке.
+	if !room.Interrupted || rm.Storage == nil {
+		if err := rm.Registry.UnregisterRoom(RoomAddress{rm.Config.InstanceID, roomId}); err != nil {
+			log.Error().Err(err).Uint("room", uint(roomId)).Msg("error while unregistering room")
+		}
+	}
+	if !room.Interrupted && rm.GameEnded != nil {
+		rm.GameEnded(room.User0.TokenHash, room.User1.TokenHash)
+	}
+	// GameMaster уже завершился, отправив RoomId, и больше не изменяет комнату.
+	room.dropReconnections()
+	delete(rm.ProcessedPlayers, room.User0.TokenHash)
+	delete(rm.ProcessedPlayers, room.User1.TokenHash)
 	delete(rm.Rooms, roomId)
-	log.Print("room RoomId = " + roomId.String() + " successfully removed")
+	metrics.ActiveRooms.Dec()
+	log.Debug().Uint("room", uint(roomId)).Msg("room removed")
 	return
 }
@@ -0,0 +1,288 @@
+package game_logic
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/user_connection"
+)
+
+func testConfig(instanceID string) Config {
+	return Config{
+		SetupTime:      time.Minute,
+		ReElectionTime: time.Minute,
+		PingInterval:   time.Minute,
+		PongWait:       time.Minute,
+		WriteWait:      time.Second,
+		MaxMessageSize: 1 << 16,
+		InstanceID:     instanceID,
+	}
+}
+
+// соединение игрока через настоящий WebSocket: серверная сторона уходит в RoomsManager,
+// клиентская читает то, что сервер отправил. closeAll закрывает клиента и сервер.
+func testConnection(t *testing.T, token string) (connection *user_connection.UserConnection, client *websocket.Conn, closeAll func()) {
+	accepted := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		accepted <- conn
+	}))
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	closeAll = func() {
+		_ = client.Close()
+		server.Close()
+	}
+	connection = &user_connection.UserConnection{
+		Login:      token,
+		Token:      token,
+		TokenHash:  user_connection.TokenHash(token),
+		Connection: <-accepted,
+		Codec:      types.JSONCodec{},
+	}
+	return
+}
+
+// ждёт "redirect" и возвращает экземпляр, куда отправили игрока.
+func readRedirect(t *testing.T, client *websocket.Conn) (instanceID string) {
+	_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, message, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal("no redirect: ", err)
+	}
+	event := types.Event{}
+	if err = event.UnmarshalJSON(message); err != nil || event.Method != "redirect" {
+		t.Fatalf("want redirect, got %s (%v)", message, err)
+	}
+	redirect := types.Redirect{}
+	if err = redirect.UnmarshalJSON(event.Parameter); err != nil {
+		t.Fatal(err)
+	}
+	instanceID = redirect.Instance
+	return
+}
+
+func TestRedirectToWaitingInstance(t *testing.T) {
+	registry := NewMemoryRegistry()
+	first := NewRoomsManager(testConfig("a"), nil, registry)
+	second := NewRoomsManager(testConfig("b"), nil, registry)
+
+	waiting, _, closeWaiting := testConnection(t, "token0")
+	defer closeWaiting()
+	first.processUserAddition(waiting)
+	if first.WaitingConnection != waiting {
+		t.Fatal("first player is not waiting")
+	}
+
+	rival, client, closeRival := testConnection(t, "token1")
+	defer closeRival()
+	second.processUserAddition(rival)
+	if instanceID := readRedirect(t, client); instanceID != "a" {
+		t.Fatalf("redirected to %q, want \"a\"", instanceID)
+	}
+	if second.WaitingConnection != nil {
+		t.Fatal("redirected player is waiting on the second instance")
+	}
+}
+
+func TestRedirectToOwnerInstance(t *testing.T) {
+	registry := NewMemoryRegistry()
+	connection, client, closeConnection := testConnection(t, "token1")
+	defer closeConnection()
+	err := registry.RegisterRoom(RoomAddress{"a", 3}, user_connection.TokenHash("token0"), connection.TokenHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rm := NewRoomsManager(testConfig("b"), nil, registry)
+	rm.processUserAddition(connection)
+	if instanceID := readRedirect(t, client); instanceID != "a" {
+		t.Fatalf("redirected to %q, want \"a\"", instanceID)
+	}
+}
+
+func TestStaleOwnRoomUnregistered(t *testing.T) {
+	registry := NewMemoryRegistry()
+	connection, _, closeConnection := testConnection(t, "token0")
+	defer closeConnection()
+	err := registry.RegisterRoom(RoomAddress{"a", 3}, connection.TokenHash, user_connection.TokenHash("token1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// сервер перезапущен без Storage: комнаты 3 больше нет.
+	rm := NewRoomsManager(testConfig("a"), nil, registry)
+	rm.processUserAddition(connection)
+	if rm.WaitingConnection != connection {
+		t.Fatal("player with a stale session is not waiting for a new game")
+	}
+	if _, found, _ := registry.FindSession(connection.TokenHash); found {
+		t.Fatal("stale room is still registered")
+	}
+}
+
+// временная папка для FileStorage, remove удаляет её.
+func testDir(t *testing.T) (dir string, remove func()) {
+	dir, err := ioutil.TempDir("", "rooms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remove = func() { _ = os.RemoveAll(dir) }
+	return
+}
+
+func testSnapshot(roomId RoomId) RoomSnapshot {
+	return RoomSnapshot{
+		OwnNumber:      roomId,
+		User0TokenHash: user_connection.TokenHash("token0_" + roomId.String()),
+		User1TokenHash: user_connection.TokenHash("token1_" + roomId.String()),
+	}
+}
+
+func TestRestoreRooms(t *testing.T) {
+	dir, remove := testDir(t)
+	defer remove()
+	storage, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots := []RoomSnapshot{testSnapshot(2), testSnapshot(5)}
+	for _, snapshot := range snapshots {
+		if err = storage.Save(snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry := NewMemoryRegistry()
+	rm := NewRoomsManager(testConfig("a"), storage, registry)
+	if err = rm.RestoreRooms(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rm.Rooms) != 2 || rm.RoomNumber != 6 {
+		t.Fatalf("restored %d rooms, next number %d", len(rm.Rooms), rm.RoomNumber)
+	}
+	for _, snapshot := range snapshots {
+		for role, tokenHash := range []string{snapshot.User0TokenHash, snapshot.User1TokenHash} {
+			game := rm.ProcessedPlayers[tokenHash]
+			if game.Room != snapshot.OwnNumber || game.Role != RoleId(role) {
+				t.Fatalf("session of room %d role %d maps to %+v", snapshot.OwnNumber, role, game)
+			}
+			room, found, _ := registry.FindSession(tokenHash)
+			if !found || room != (RoomAddress{"a", snapshot.OwnNumber}) {
+				t.Fatalf("session of room %d role %d registered as %+v, %v", snapshot.OwnNumber, role, room, found)
+			}
+		}
+	}
+
+	// прерванные остановкой сервера комнаты остаются в реестре до следующего запуска.
+	rm.processDrain(time.Now())
+	for range snapshots {
+		select {
+		case roomId := <-rm.CompletedRooms:
+			_ = rm.processRoomRemoval(roomId)
+		case <-time.After(5 * time.Second):
+			t.Fatal("room is not interrupted by drain")
+		}
+	}
+	for _, snapshot := range snapshots {
+		if _, found, _ := registry.FindSession(snapshot.User0TokenHash); !found {
+			t.Fatalf("interrupted room %d is unregistered", snapshot.OwnNumber)
+		}
+	}
+	if loaded, _ := storage.LoadAll(); len(loaded) != len(snapshots) {
+		t.Fatalf("%d snapshots left after drain, want %d", len(loaded), len(snapshots))
+	}
+}
+
+// реестр, отказывающий в регистрации одной комнаты.
+type failingRegistry struct {
+	*MemoryRegistry
+	failOn RoomId
+}
+
+func (fr failingRegistry) RegisterRoom(room RoomAddress, token0 string, token1 string) (err error) {
+	if room.Room == fr.failOn {
+		err = errors.New("registry is unavailable")
+		return
+	}
+	return fr.MemoryRegistry.RegisterRoom(room, token0, token1)
+}
+
+func TestRestoreRoomsRegistryError(t *testing.T) {
+	dir, remove := testDir(t)
+	defer remove()
+	storage, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots := []RoomSnapshot{testSnapshot(1), testSnapshot(2), testSnapshot(3)}
+	for _, snapshot := range snapshots {
+		if err = storage.Save(snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry := failingRegistry{MemoryRegistry: NewMemoryRegistry(), failOn: 2}
+	rm := NewRoomsManager(testConfig("a"), storage, registry)
+	if err = rm.RestoreRooms(); err == nil {
+		t.Fatal("registry error is not returned")
+	}
+	if len(rm.Rooms) != 0 || len(rm.ProcessedPlayers) != 0 {
+		t.Fatalf("%d rooms started despite registry error", len(rm.Rooms))
+	}
+	for _, snapshot := range snapshots {
+		if _, found, _ := registry.FindSession(snapshot.User0TokenHash); found {
+			t.Fatalf("room %d is left registered", snapshot.OwnNumber)
+		}
+	}
+}
+
+func TestFinishedRoomUnregisteredWhileDraining(t *testing.T) {
+	dir, remove := testDir(t)
+	defer remove()
+	storage, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := NewMemoryRegistry()
+	rm := NewRoomsManager(testConfig("a"), storage, registry)
+	rm.Draining = true
+
+	for _, interrupted := range []bool{false, true} {
+		snapshot := testSnapshot(rm.RoomNumber)
+		room := newRoom(
+			&user_connection.UserConnection{TokenHash: snapshot.User0TokenHash},
+			&user_connection.UserConnection{TokenHash: snapshot.User1TokenHash},
+			rm.CompletedRooms, snapshot.OwnNumber, rm.Config, rm.Storage)
+		room.Interrupted = interrupted
+		rm.Rooms[snapshot.OwnNumber] = room
+		err = registry.RegisterRoom(RoomAddress{"a", snapshot.OwnNumber}, snapshot.User0TokenHash, snapshot.User1TokenHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rm.RoomNumber++
+
+		if err = rm.processRoomRemoval(snapshot.OwnNumber); err != nil {
+			t.Fatal(err)
+		}
+		if _, found, _ := registry.FindSession(snapshot.User0TokenHash); found != interrupted {
+			t.Fatalf("interrupted %v: room registered %v", interrupted, found)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package game_logic
+
+import (
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/user_connection"
+)
+
+// Состояние незавершённой игры, сохраняемое в Storage после каждого изменения.
+// Соединения и таймеры не сохраняются: их нельзя перенести в другой процесс.
+// Токены сессий не сохраняются, только их хеши: игрок узнаётся по хешу, когда переподключится.
+type RoomSnapshot struct {
+	OwnNumber               RoomId
+	User0TokenHash          string
+	User1TokenHash          string
+	Map                     Map
+	User0UploadedCharacters bool
+	User1UploadedCharacters bool
+	UserTurnNumber          RoleId
+	CreatedAt               time.Time
+	WeaponReElection        struct {
+		WaitingForIt       bool
+		User0ReElect       bool
+		User1ReElect       bool
+		AttackingCharacter int
+		AttackedCharacter  int
+		ConsecutiveTies    int
+	}
+}
+
+// ответственность: снимок состояния комнаты, вызывается только из GameMaster.
+func (r *Room) Snapshot() (snapshot RoomSnapshot) {
+	snapshot = RoomSnapshot{
+		OwnNumber:               r.OwnNumber,
+		User0TokenHash:          r.User0.TokenHash,
+		User1TokenHash:          r.User1.TokenHash,
+		Map:                     r.Map,
+		User0UploadedCharacters: r.User0UploadedCharacters,
+		User1UploadedCharacters: r.User1UploadedCharacters,
+		UserTurnNumber:          r.UserTurnNumber,
+		CreatedAt:               r.CreatedAt,
+	}
+	snapshot.WeaponReElection = r.WeaponReElection
+	return
+}
+
+// ответственность: сохраняет состояние комнаты в Storage, если оно задано.
+// Ошибка только логируется: игра продолжается, но может не пережить перезапуск.
+func (r *Room) persist() {
+	if r.Storage == nil {
+		return
+	}
+	err := r.Storage.Save(r.Snapshot())
+	if err != nil {
+		r.Logger.Error().Err(err).Msg("error while saving snapshot")
+	}
+	return
+}
+
+// ответственность: удаляет состояние законченной игры из Storage.
+func (r *Room) forget() {
+	if r.Storage == nil {
+		return
+	}
+	err := r.Storage.Delete(r.OwnNumber)
+	if err != nil {
+		r.Logger.Error().Err(err).Msg("error while deleting snapshot")
+	}
+	return
+}
+
+// Восстанавливает комнату после перезапуска сервера. Игроки ещё не подключены:
+// соединения появятся через Reconnect, когда игроки придут с теми же SessionId.
+func RestoreRoom(snapshot RoomSnapshot, completedRooms chan RoomId, config Config, storage Storage) (room *Room) {
+	room = newRoom(
+		&user_connection.UserConnection{TokenHash: snapshot.User0TokenHash},
+		&user_connection.UserConnection{TokenHash: snapshot.User1TokenHash},
+		completedRooms, snapshot.OwnNumber, config, storage)
+	room.Map = snapshot.Map
+	room.User0UploadedCharacters = snapshot.User0UploadedCharacters
+	room.User1UploadedCharacters = snapshot.User1UploadedCharacters
+	room.UserTurnNumber = snapshot.UserTurnNumber
+	room.CreatedAt = snapshot.CreatedAt
+	room.WeaponReElection = snapshot.WeaponReElection
+	if room.User0UploadedCharacters && room.User1UploadedCharacters {
+		room.SetupTimer.Stop()
+	}
+	if room.WeaponReElection.WaitingForIt {
+		room.ReElectionTimer.Reset(config.ReElectionTime)
+	}
+	room.start()
+	room.Logger.Info().Str("session0", logging.SessionOfHash(room.User0.TokenHash)).
+		Str("session1", logging.SessionOfHash(room.User1.TokenHash)).Msg("room restored")
+	return
+}
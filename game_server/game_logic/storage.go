@@ -0,0 +1,96 @@
+package game_logic
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Долговременное хранилище состояний комнат. Комната сохраняет себя после каждого
+// изменения состояния и удаляет после конца игры, RoomsManager при старте
+// восстанавливает все сохранённые комнаты. Save и Delete вызываются из горутин
+// разных комнат одновременно, но для одной комнаты - последовательно.
+type Storage interface {
+	Save(snapshot RoomSnapshot) (err error)
+	Delete(roomId RoomId) (err error)
+	LoadAll() (snapshots []RoomSnapshot, err error)
+}
+
+// Хранилище в директории на диске, по файлу room_<RoomId>.json на комнату.
+type FileStorage struct {
+	Dir string
+}
+
+func NewFileStorage(dir string) (storage *FileStorage, err error) {
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		err = errors.Wrap(err, "in os.MkdirAll: ")
+		return
+	}
+	storage = &FileStorage{Dir: dir}
+	return
+}
+
+func (fs *FileStorage) fileName(roomId RoomId) string {
+	return filepath.Join(fs.Dir, "room_"+roomId.String()+".json")
+}
+
+// запись во временный файл и переименование, что бы при падении посреди записи
+// остался предыдущий целый снимок. Снимок читает только сам сервер.
+func (fs *FileStorage) Save(snapshot RoomSnapshot) (err error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		err = errors.Wrap(err, "in json.Marshal RoomSnapshot: ")
+		return
+	}
+	fileName := fs.fileName(snapshot.OwnNumber)
+	err = ioutil.WriteFile(fileName+".tmp", data, 0600)
+	if err != nil {
+		err = errors.Wrap(err, "in ioutil.WriteFile: ")
+		return
+	}
+	err = os.Rename(fileName+".tmp", fileName)
+	if err != nil {
+		err = errors.Wrap(err, "in os.Rename: ")
+	}
+	return
+}
+
+func (fs *FileStorage) Delete(roomId RoomId) (err error) {
+	err = os.Remove(fs.fileName(roomId))
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, "in os.Remove: ")
+	}
+	return
+}
+
+func (fs *FileStorage) LoadAll() (snapshots []RoomSnapshot, err error) {
+	files, err := ioutil.ReadDir(fs.Dir)
+	if err != nil {
+		err = errors.Wrap(err, "in ioutil.ReadDir: ")
+		return
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "room_") || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(fs.Dir, file.Name()))
+		if err != nil {
+			return nil, errors.Wrap(err, "in ioutil.ReadFile: ")
+		}
+		snapshot := RoomSnapshot{}
+		err = json.Unmarshal(data, &snapshot)
+		if err != nil {
+			return nil, errors.Wrap(err, "in json.Unmarshal '"+file.Name()+"': ")
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return
+}
@@ -0,0 +1,93 @@
+// Нагрузочный генератор игрового сервера: открывает --clients WebSocket соединений,
+// игроки попарно попадают в комнаты и играют через настоящий протокол, пока не истечёт --duration.
+// В конце печатает пропускную способность, перцентили задержки хода, ошибки
+// и память сервера на комнату по его /metrics.
+package main
+
+import (
+	"github.com/rs/zerolog/log"
+	flag "github.com/spf13/pflag" // ради gnu style: --flag='value'
+	"os"
+	"sync"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
+)
+
+// настройки нагрузки, одни на всех игроков.
+type Config struct {
+	// адрес входной точки игрового сервера.
+	URL string
+	// формат сообщений: types.SubprotocolJSON или types.SubprotocolMessagePack.
+	Subprotocol string
+	// "aggressive" - персонажи идут вперёд и нападают, игры быстро заканчиваются,
+	// "random" - любой допустимый ход.
+	Strategy string
+	// пауза перед каждым ходом, имитирует раздумья игрока.
+	MoveDelay time.Duration
+	// после этого момента игроки закрывают соединения и новые игры не начинаются.
+	Deadline time.Time
+}
+
+func main() {
+	config := Config{}
+	flag.StringVar(&config.URL, "url", "ws://localhost:8080/game/v1/entrypoint", "game server entrypoint")
+	metricsURL := flag.String("metrics-url", "http://localhost:8080/metrics",
+		"game server prometheus metrics for memory per room, empty - do not measure")
+	clients := flag.Int("clients", 1000, "number of concurrent players, two players per room")
+	rampUp := flag.Duration("ramp-up", 10*time.Second, "time over which the players connect")
+	duration := flag.Duration("duration", time.Minute, "test duration, players start new games until it expires")
+	codec := flag.String("codec", "json", "message format: 'json' or 'msgpack'")
+	flag.StringVar(&config.Strategy, "strategy", "aggressive",
+		"how players move: 'aggressive' - forward and attack, games end quickly, 'random' - any legal move")
+	flag.DurationVar(&config.MoveDelay, "move-delay", 100*time.Millisecond, "pause before each move")
+	logLevel := flag.String("log-level", "warn", "minimal log level: 'debug', 'info', 'warn' or 'error'")
+	flag.Parse()
+	err := logging.Setup(*logLevel, "console")
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid logging flags")
+	}
+	switch *codec {
+	case "json":
+		config.Subprotocol = types.SubprotocolJSON
+	case "msgpack":
+		config.Subprotocol = types.SubprotocolMessagePack
+	default:
+		log.Fatal().Msg("unknown --codec '" + *codec + "', available only ['json', 'msgpack']")
+	}
+	if config.Strategy != "aggressive" && config.Strategy != "random" {
+		log.Fatal().Msg("unknown --strategy '" + config.Strategy + "', available only ['aggressive', 'random']")
+	}
+	if *clients < 2 {
+		log.Fatal().Msg("--clients must be at least 2")
+	}
+
+	stats := NewStats()
+	var monitor *Monitor
+	if *metricsURL != "" {
+		monitor, err = NewMonitor(*metricsURL)
+		if err != nil {
+			log.Fatal().Err(err).Msg("can not read server metrics")
+		}
+		go monitor.Run()
+	}
+	start := time.Now()
+	config.Deadline = start.Add(*duration)
+	waitGroup := sync.WaitGroup{}
+	for i := 0; i < *clients; i++ {
+		waitGroup.Add(1)
+		delay := *rampUp * time.Duration(i) / time.Duration(*clients)
+		go func() {
+			defer waitGroup.Done()
+			time.Sleep(delay)
+			Play(config, stats)
+		}()
+	}
+	waitGroup.Wait()
+	if monitor != nil {
+		monitor.Stop()
+	}
+	stats.Report(os.Stdout, time.Since(start), monitor)
+	return
+}
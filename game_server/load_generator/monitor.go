@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// показатели сервера из его /metrics.
+type Sample struct {
+	Rooms      float64
+	Goroutines float64
+	HeapInUse  float64
+	Resident   float64
+}
+
+// Monitor раз в секунду читает /metrics сервера и запоминает момент с наибольшим числом комнат.
+// Память на комнату - прирост относительно замера до начала нагрузки, делённый на число комнат.
+type Monitor struct {
+	URL      string
+	Baseline Sample
+	mutex    sync.Mutex
+	Peak     Sample
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func NewMonitor(url string) (monitor *Monitor, err error) {
+	monitor = &Monitor{
+		URL:     url,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	monitor.Baseline, err = monitor.sample()
+	return
+}
+
+func (m *Monitor) Run() {
+	defer close(m.stopped)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			sample, err := m.sample()
+			if err != nil {
+				continue
+			}
+			m.mutex.Lock()
+			if sample.Rooms >= m.Peak.Rooms {
+				m.Peak = sample
+			}
+			m.mutex.Unlock()
+		}
+	}
+}
+
+func (m *Monitor) Stop() {
+	close(m.stop)
+	<-m.stopped
+	return
+}
+
+// разбирает текстовый формат Prometheus, нужны только метрики без меток.
+func (m *Monitor) sample() (sample Sample, err error) {
+	response, err := http.Get(m.URL)
+	if err != nil {
+		err = errors.Wrap(err, "in http.Get metrics: ")
+		return
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusOK {
+		err = errors.New("metrics status " + response.Status)
+		return
+	}
+	values := map[string]*float64{
+		"game_active_rooms":             &sample.Rooms,
+		"go_goroutines":                 &sample.Goroutines,
+		"go_memstats_heap_inuse_bytes":  &sample.HeapInUse,
+		"process_resident_memory_bytes": &sample.Resident,
+	}
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, ok := values[fields[0]]; ok {
+			*value, _ = strconv.ParseFloat(fields[1], 64)
+		}
+	}
+	err = scanner.Err()
+	return
+}
+
+func (m *Monitor) Report(output io.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	_, _ = fmt.Fprintf(output, "server peak            rooms=%.0f goroutines=%.0f heap_inuse=%.1fMiB rss=%.1fMiB\n",
+		m.Peak.Rooms, m.Peak.Goroutines, m.Peak.HeapInUse/(1<<20), m.Peak.Resident/(1<<20))
+	rooms := m.Peak.Rooms - m.Baseline.Rooms
+	if rooms <= 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(output, "per room               goroutines=%.1f heap_inuse=%.1fKiB rss=%.1fKiB\n",
+		(m.Peak.Goroutines-m.Baseline.Goroutines)/rooms,
+		(m.Peak.HeapInUse-m.Baseline.HeapInUse)/rooms/(1<<10),
+		(m.Peak.Resident-m.Baseline.Resident)/rooms/(1<<10))
+	return
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
+)
+
+// клетка карты, как types.MapCell, но разбирается encoding/json:
+// easyjson считает "weapon": null отсутствующим обязательным полем.
+type Cell struct {
+	User   bool    `json:"user"`
+	Weapon *string `json:"weapon"`
+}
+
+// Игрок: одно соединение, одна партия за раз. Карта хранится так, как её видит клиент:
+// свои персонажи снизу, вперёд - это на 7 клеток меньше.
+type Player struct {
+	Config Config
+	Stats  *Stats
+	// cookie "SessionId", новая для каждой партии.
+	Token      string
+	Connection *websocket.Conn
+	Codec      types.Codec
+	Map        [42]*Cell
+	MyTurn     bool
+	// идёт перевыбор оружия, ходить нельзя.
+	ReElection bool
+	// момент отправки ещё не разрешённого хода, нулевой - хода нет.
+	MoveSent  time.Time
+	GameStart time.Time
+	// адрес из "redirect", по которому надо переподключиться.
+	Redirect string
+}
+
+// играет партии одну за другой до Config.Deadline.
+func Play(config Config, stats *Stats) {
+	player := &Player{
+		Config: config,
+		Stats:  stats,
+		Codec:  types.CodecBySubprotocol(config.Subprotocol),
+	}
+	for time.Now().Before(config.Deadline) {
+		player.Token = strconv.FormatUint(rand.Uint64(), 36) + strconv.FormatUint(rand.Uint64(), 36)
+		err := player.dial(config.URL)
+		if err != nil {
+			stats.Error("dial")
+			log.Warn().Err(err).Msg("dial")
+			// сервер перегружен или не запущен, не долбим его в цикле.
+			time.Sleep(time.Second)
+			continue
+		}
+		player.game()
+	}
+	return
+}
+
+func (p *Player) dial(address string) (err error) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		Subprotocols:     []string{p.Config.Subprotocol},
+	}
+	header := http.Header{}
+	header.Set("Cookie", "SessionId="+p.Token)
+	p.Connection, _, err = dialer.Dial(address, header)
+	return
+}
+
+// ответственность: одна партия от подключения до "gameover" или Config.Deadline.
+func (p *Player) game() {
+	p.Map = [42]*Cell{}
+	p.MyTurn, p.ReElection, p.MoveSent = false, false, time.Time{}
+	p.GameStart = time.Now()
+	p.Stats.GameStarted()
+	defer func() {
+		_ = p.Connection.Close()
+		return
+	}()
+	_ = p.Connection.SetReadDeadline(p.Config.Deadline)
+	p.send("upload_random_map", nil)
+	for {
+		_, data, err := p.Connection.ReadMessage()
+		if err != nil {
+			if time.Now().Before(p.Config.Deadline) {
+				p.Stats.Error("read")
+				log.Warn().Err(err).Msg("read")
+			}
+			return
+		}
+		message, err := p.Codec.Decode(data)
+		if err != nil {
+			p.Stats.Error("decode")
+			continue
+		}
+		p.Stats.MessageReceived()
+		event := types.Event{}
+		if err = event.UnmarshalJSON(message); err != nil {
+			p.Stats.Error("decode")
+			continue
+		}
+		if gameOver := p.handle(event); gameOver {
+			return
+		}
+		if p.Redirect != "" {
+			if !p.followRedirect() {
+				return
+			}
+			continue
+		}
+		if p.MyTurn && !p.ReElection && p.MoveSent.IsZero() {
+			time.Sleep(p.Config.MoveDelay)
+			p.move()
+		}
+	}
+}
+
+// ответственность: обновляет карту по сообщению сервера. Возвращает true, если партия закончена.
+func (p *Player) handle(event types.Event) (gameOver bool) {
+	switch event.Method {
+	case "download_map":
+		p.Map = [42]*Cell{}
+		if err := json.Unmarshal(event.Parameter, &p.Map); err != nil {
+			p.Stats.Error("decode")
+		}
+	case "your_turn":
+		p.MyTurn = string(event.Parameter) == "true"
+	case "move_character":
+		move := types.MoveCharacter{}
+		_ = move.UnmarshalJSON(event.Parameter)
+		p.Map[move.To], p.Map[move.From] = p.Map[move.From], nil
+		// ход перешёл, следом придёт "your_turn".
+		p.MyTurn = false
+		p.resolved()
+	case "attack":
+		attack := types.Attack{}
+		_ = attack.UnmarshalJSON(event.Parameter)
+		p.attack(attack)
+		p.MyTurn, p.ReElection = false, false
+		p.resolved()
+	case "add_weapon":
+		addWeapon := types.AddWeapon{}
+		_ = addWeapon.UnmarshalJSON(event.Parameter)
+		if cell := p.Map[addWeapon.Coordinates]; cell != nil {
+			cell.Weapon = &addWeapon.Weapon
+		}
+	case "weapon_change_request":
+		request := types.WeaponChangeRequest{}
+		_ = request.UnmarshalJSON(event.Parameter)
+		p.ReElection = true
+		p.resolved()
+		weapons := []string{"rock", "scissors", "paper"}
+		parameter, _ := types.ReassignWeapons{
+			NewWeapon:         weapons[rand.Intn(len(weapons))],
+			CharacterPosition: request.CharacterPosition,
+		}.MarshalJSON()
+		p.send("reassign_weapons", parameter)
+	case "error_message":
+		errorMessage := types.ErrorMessage{}
+		_ = errorMessage.UnmarshalJSON(event.Parameter)
+		p.Stats.Error("error_message:" + errorMessage.Code)
+		// следом придёт "download_map", ход можно повторить.
+		p.MoveSent = time.Time{}
+	case "redirect":
+		redirect := types.Redirect{}
+		_ = redirect.UnmarshalJSON(event.Parameter)
+		p.Stats.Redirected()
+		p.Redirect = redirect.URL
+	case "server_shutdown":
+		p.Stats.Error("server_shutdown")
+	case "gameover", "technical_gameover":
+		p.resolved()
+		p.Stats.GameFinished(time.Since(p.GameStart))
+		gameOver = true
+	}
+	return
+}
+
+// ход, отправленный этим игроком, разрешился: записывает задержку до рассылки результата.
+func (p *Player) resolved() {
+	if !p.MoveSent.IsZero() {
+		p.Stats.MoveLatency(time.Since(p.MoveSent))
+		p.MoveSent = time.Time{}
+	}
+	return
+}
+
+// ответственность: бой на карте клиента. Ход ещё не передан, поэтому нападает тот, чей сейчас ход.
+func (p *Player) attack(attack types.Attack) {
+	winner := p.Map[attack.Winner.Coordinates]
+	if winner == nil {
+		// карта разошлась с серверной, её поправит следующий "download_map".
+		return
+	}
+	winner.Weapon = &attack.Winner.Weapon
+	if winner.User == p.MyTurn {
+		// нападающий победил и занял клетку проигравшего.
+		p.Map[attack.Loser.Coordinates], p.Map[attack.Winner.Coordinates] = winner, nil
+	} else {
+		p.Map[attack.Loser.Coordinates] = nil
+	}
+	return
+}
+
+// ответственность: выбирает и отправляет ход согласно Config.Strategy.
+func (p *Player) move() {
+	type candidate struct {
+		from, to, score int
+	}
+	var candidates []candidate
+	best := 0
+	for from, cell := range p.Map {
+		if cell == nil || !cell.User || (cell.Weapon != nil && *cell.Weapon == "flag") {
+			continue
+		}
+		for _, to := range neighbours(from) {
+			target := p.Map[to]
+			if target != nil && target.User {
+				continue
+			}
+			score := 0
+			if p.Config.Strategy == "aggressive" {
+				switch {
+				case target != nil:
+					score = 3
+				case to == from-7:
+					score = 2
+				case to != from+7:
+					score = 1
+				}
+			}
+			if score > best {
+				best, candidates = score, candidates[:0]
+			}
+			if score == best {
+				candidates = append(candidates, candidate{from, to, score})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		// ходить некем, партия закончится по таймауту сервера.
+		return
+	}
+	chosen := candidates[rand.Intn(len(candidates))]
+	parameter, _ := types.AttemptGoToCell{
+		From: chosen.from,
+		To:   chosen.to,
+	}.MarshalJSON()
+	p.MoveSent = time.Now()
+	p.send("attempt_go_to_cell", parameter)
+	p.Stats.MoveSent()
+	return
+}
+
+// соседние по горизонтали и вертикали клетки поля 7 x 6.
+func neighbours(cell int) (result []int) {
+	if cell >= 7 {
+		result = append(result, cell-7)
+	}
+	if cell+7 < 42 {
+		result = append(result, cell+7)
+	}
+	if cell%7 != 0 {
+		result = append(result, cell-1)
+	}
+	if cell%7 != 6 {
+		result = append(result, cell+1)
+	}
+	return
+}
+
+func (p *Player) send(method string, parameter []byte) {
+	if parameter == nil {
+		parameter = []byte("null")
+	}
+	message, _ := types.Event{
+		Method:    method,
+		Parameter: parameter,
+	}.MarshalJSON()
+	data, err := p.Codec.Encode(message)
+	if err != nil {
+		p.Stats.Error("encode")
+		return
+	}
+	messageType := websocket.TextMessage
+	if p.Codec.Binary() {
+		messageType = websocket.BinaryMessage
+	}
+	_ = p.Connection.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err = p.Connection.WriteMessage(messageType, data); err != nil && time.Now().Before(p.Config.Deadline) {
+		p.Stats.Error("write")
+	}
+	return
+}
+
+// переподключается по адресу из "redirect" с той же cookie: схема и хост из Config.URL, путь от сервера.
+func (p *Player) followRedirect() (ok bool) {
+	_ = p.Connection.Close()
+	address, err := url.Parse(p.Config.URL)
+	if err != nil {
+		return
+	}
+	address.Path = p.Redirect
+	p.Redirect = ""
+	err = p.dial(address.String())
+	if err != nil {
+		p.Stats.Error("dial")
+		log.Warn().Err(err).Msg("redirect")
+		return
+	}
+	_ = p.Connection.SetReadDeadline(p.Config.Deadline)
+	p.send("upload_random_map", nil)
+	ok = true
+	return
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stats собирает результаты всех игроков, методы вызываются из их горутин.
+type Stats struct {
+	mutex            sync.Mutex
+	gamesStarted     int
+	gamesFinished    int
+	gameDurations    []time.Duration
+	movesSent        int
+	messagesReceived int
+	redirects        int
+	// от отправки "attempt_go_to_cell" до прихода результата хода, разосланного обоим игрокам.
+	moveLatencies []time.Duration
+	// ключ - "dial", "read", "write", "decode", "encode" или "error_message:<code>".
+	errors map[string]int
+}
+
+func NewStats() (stats *Stats) {
+	stats = &Stats{
+		errors: make(map[string]int),
+	}
+	return
+}
+
+func (s *Stats) GameStarted() {
+	s.mutex.Lock()
+	s.gamesStarted++
+	s.mutex.Unlock()
+	return
+}
+
+func (s *Stats) GameFinished(duration time.Duration) {
+	s.mutex.Lock()
+	s.gamesFinished++
+	s.gameDurations = append(s.gameDurations, duration)
+	s.mutex.Unlock()
+	return
+}
+
+func (s *Stats) MoveSent() {
+	s.mutex.Lock()
+	s.movesSent++
+	s.mutex.Unlock()
+	return
+}
+
+func (s *Stats) MessageReceived() {
+	s.mutex.Lock()
+	s.messagesReceived++
+	s.mutex.Unlock()
+	return
+}
+
+func (s *Stats) Redirected() {
+	s.mutex.Lock()
+	s.redirects++
+	s.mutex.Unlock()
+	return
+}
+
+func (s *Stats) MoveLatency(latency time.Duration) {
+	s.mutex.Lock()
+	s.moveLatencies = append(s.moveLatencies, latency)
+	s.mutex.Unlock()
+	return
+}
+
+func (s *Stats) Error(kind string) {
+	s.mutex.Lock()
+	s.errors[kind]++
+	s.mutex.Unlock()
+	return
+}
+
+// значение перцентиля percent (0 < percent <= 100) отсортированного среза.
+func percentile(sorted []time.Duration, percent float64) (value time.Duration) {
+	if len(sorted) == 0 {
+		return
+	}
+	index := int(float64(len(sorted))*percent/100+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	value = sorted[index]
+	return
+}
+
+func durations(title string, values []time.Duration) (line string) {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	line = fmt.Sprintf("%-22s n=%d p50=%v p90=%v p99=%v max=%v\n", title, len(values),
+		percentile(values, 50), percentile(values, 90), percentile(values, 99), percentile(values, 100))
+	return
+}
+
+// печатает итоги нагрузки, monitor == nil - без данных сервера.
+func (s *Stats) Report(output io.Writer, elapsed time.Duration, monitor *Monitor) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	seconds := elapsed.Seconds()
+	_, _ = fmt.Fprintf(output, "elapsed                %v\n", elapsed.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(output, "games                  started=%d finished=%d (%.2f/s) unfinished=%d\n",
+		s.gamesStarted, s.gamesFinished, float64(s.gamesFinished)/seconds, s.gamesStarted-s.gamesFinished)
+	_, _ = fmt.Fprintf(output, "moves                  %d (%.1f/s)\n", s.movesSent, float64(s.movesSent)/seconds)
+	_, _ = fmt.Fprintf(output, "messages received      %d (%.1f/s)\n", s.messagesReceived, float64(s.messagesReceived)/seconds)
+	_, _ = fmt.Fprintf(output, "redirects              %d\n", s.redirects)
+	_, _ = fmt.Fprint(output, durations("move to broadcast", s.moveLatencies))
+	_, _ = fmt.Fprint(output, durations("game duration", s.gameDurations))
+	kinds := make([]string, 0, len(s.errors))
+	for kind := range s.errors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	_, _ = fmt.Fprintf(output, "errors                 %d kinds\n", len(kinds))
+	for _, kind := range kinds {
+		_, _ = fmt.Fprintf(output, "    %-40s %d\n", kind, s.errors[kind])
+	}
+	if monitor != nil {
+		monitor.Report(output)
+	}
+	return
+}
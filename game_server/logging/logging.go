@@ -0,0 +1,57 @@
+// Настройка единого структурированного логгера zerolog.
+// Все пакеты пишут через github.com/rs/zerolog/log, добавляя поля:
+// "room", "role", "login", "request_id", "session" (только через Session).
+
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// настраивает глобальный логгер: level ∈ ["debug", "info", "warn", "error"],
+// format ∈ ["json", "console"], console - для чтения человеком при разработке.
+func Setup(level string, format string) (err error) {
+	logLevel, err := zerolog.ParseLevel(level)
+	if err != nil {
+		err = errors.Wrap(err, "unknown log level '"+level+"': ")
+		return
+	}
+	zerolog.SetGlobalLevel(logLevel)
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	switch format {
+	case "json":
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+	case "console":
+		log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
+	default:
+		err = errors.New("unknown log format '" + format + "', available only ['json', 'console']")
+	}
+	return
+}
+
+// токен сессии даёт доступ к аккаунту, поэтому в лог пишется только начало его sha256:
+// этого достаточно, что бы связать записи одной сессии.
+func Session(token string) (redacted string) {
+	if token == "" {
+		return
+	}
+	hash := sha256.Sum256([]byte(token))
+	redacted = hex.EncodeToString(hash[:4])
+	return
+}
+
+// то же, что Session, по sha256 токена в hex, когда самого токена нет.
+func SessionOfHash(tokenHash string) (redacted string) {
+	if len(tokenHash) < 8 {
+		return
+	}
+	redacted = tokenHash[:8]
+	return
+}
@@ -1,28 +1,146 @@
 package main
 
 import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
 	flag "github.com/spf13/pflag" // ради gnu style: --flag='value'
-	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/admin"
 	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/connection_upgrader"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/game_end_hook"
 	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/game_logic"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/logging"
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/protocol_schema"
 	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/websocket_test_page"
 )
 
 func main() {
 	listenPort := flag.Uint16("listen-port", 8080, "listen port for websocket server")
 	// authorisationServerPort := flag.Uint16("authorisation-port", 8081, "port for grpc connection to the authentication server")
+	gameConfig := game_logic.Config{}
+	flag.DurationVar(&gameConfig.SetupTime, "setup-time", 2*time.Minute,
+		"time for both players to upload their characters after the room is created")
+	flag.StringVar(&gameConfig.SetupTimeoutPolicy, "setup-timeout-policy", game_logic.SetupTimeoutGenerate,
+		"what to do with a player who did not upload characters in time: 'generate' random ones or 'forfeit' the game")
+	flag.DurationVar(&gameConfig.ReElectionTime, "re-election-time", 30*time.Second,
+		"time for both players to answer 'weapon_change_request' with 'reassign_weapons'")
+	flag.StringVar(&gameConfig.ReElectionPolicy, "re-election-policy", game_logic.ReElectionRandom,
+		"how a tie is resolved without the players: assign 'random' different weapons or 'attacker_loses'")
+	flag.IntVar(&gameConfig.MaxConsecutiveTies, "max-consecutive-ties", 3,
+		"how many ties in a row one fight may have before --re-election-policy is applied, 0 - unlimited")
+	flag.DurationVar(&gameConfig.PingInterval, "ping-interval", 30*time.Second,
+		"how often the server sends websocket 'ping' to players, must be less than --pong-wait")
+	flag.DurationVar(&gameConfig.PongWait, "pong-wait", time.Minute,
+		"how long to wait for any message or 'pong' from a player before the connection is considered lost")
+	flag.DurationVar(&gameConfig.WriteWait, "write-wait", 10*time.Second,
+		"time limit for writing one message to a player")
+	flag.Int64Var(&gameConfig.MaxMessageSize, "max-message-size", 4096,
+		"maximum size of an incoming websocket message in bytes")
+	flag.StringVar(&gameConfig.InstanceID, "instance-id", "instance1",
+		"name of this game server instance, unique among all instances, served at /game/v1/<instance-id>/entrypoint")
+	registryDSN := flag.String("registry-postgres", "",
+		"postgres data source name of the registry shared by all instances, empty - in-memory registry for a single instance")
+	drainTime := flag.Duration("drain-time", time.Minute,
+		"on SIGTERM: how long running games may continue before they are interrupted until the next start")
+	storageDir := flag.String("storage-dir", "/var/lib/game_server/rooms",
+		"directory where running games are saved to survive a restart, empty - keep games only in memory")
+	logLevel := flag.String("log-level", "info", "minimal log level: 'debug', 'info', 'warn' or 'error'")
+	logFormat := flag.String("log-format", "json", "log output: 'json' or human readable 'console'")
+	adminToken := flag.String("admin-token", "",
+		"bearer token for /game/v1/admin/ API, empty - admin API is disabled")
+	gameEndURL := flag.String("game-end-url", "",
+		"authorization server address notified of finished games to delete their disposable users, "+
+			"like 'http://authorization:8080/api/v1/internal/game_ended'; empty - do not notify")
+	internalToken := flag.String("internal-token", os.Getenv("INTERNAL_TOKEN"),
+		"--internal-token of the authorization server for --game-end-url, defaults to $INTERNAL_TOKEN")
 	flag.Parse()
+	err := logging.Setup(*logLevel, *logFormat)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid logging flags")
+	}
+	if gameConfig.PingInterval >= gameConfig.PongWait {
+		log.Fatal().Msg("--ping-interval must be less than --pong-wait")
+	}
+	if gameConfig.ReElectionPolicy != game_logic.ReElectionRandom &&
+		gameConfig.ReElectionPolicy != game_logic.ReElectionAttackerLoses {
+		log.Fatal().Msg("unknown --re-election-policy '" + gameConfig.ReElectionPolicy + "', " +
+			"available only ['random', 'attacker_loses']")
+	}
+	if gameConfig.SetupTimeoutPolicy != game_logic.SetupTimeoutGenerate &&
+		gameConfig.SetupTimeoutPolicy != game_logic.SetupTimeoutForfeit {
+		log.Fatal().Msg("unknown --setup-timeout-policy '" + gameConfig.SetupTimeoutPolicy + "', " +
+			"available only ['generate', 'forfeit']")
+	}
 	// TODO: Написать подсервер проверки авторизации приходящего соединения (cookie -> login).
 	// Инициализируем upgrader - он превращает соединения в websocket.
 	upgrader := connectionUpgrader.NewConnectionUpgrader()
-	roomsManager := game_logic.NewRoomsManager()
+	var storage game_logic.Storage
+	if *storageDir != "" {
+		fileStorage, err := game_logic.NewFileStorage(*storageDir)
+		if err != nil {
+			log.Fatal().Err(err).Msg("can not open room storage")
+		}
+		storage = fileStorage
+	}
+	var registry game_logic.Registry = game_logic.NewMemoryRegistry()
+	if *registryDSN != "" {
+		registry, err = game_logic.NewPostgresRegistry(*registryDSN)
+		if err != nil {
+			log.Fatal().Err(err).Msg("can not connect to registry")
+		}
+	}
+	roomsManager := game_logic.NewRoomsManager(gameConfig, storage, registry)
+	if *gameEndURL != "" {
+		roomsManager.GameEnded = game_end_hook.NewHook(*gameEndURL, *internalToken).GameEnded
+	}
+	err = roomsManager.RestoreRooms()
+	if err != nil {
+		log.Fatal().Err(err).Msg("can not restore rooms")
+	}
 	go roomsManager.Run(upgrader.QueueToGame)
+	// общий адрес для первого подключения и адрес этого экземпляра для "redirect".
 	http.HandleFunc("/game/v1/entrypoint", upgrader.HTTPEntryPoint)
+	http.HandleFunc("/game/v1/"+gameConfig.InstanceID+"/entrypoint", upgrader.HTTPEntryPoint)
+	http.HandleFunc("/game/v1/protocol", protocol_schema.ProtocolSchema)
+	http.Handle("/metrics", promhttp.Handler())
+	adminAPI := admin.NewAdmin(roomsManager, *adminToken)
+	http.HandleFunc("/game/v1/admin/rooms", adminAPI.Rooms)
+	http.HandleFunc("/game/v1/admin/room", adminAPI.Room)
+	http.HandleFunc("/game/v1/admin/room/end", adminAPI.EndRoom)
+	http.HandleFunc("/game/v1/admin/kick", adminAPI.Kick)
 	http.HandleFunc("/", websocket_test_page.WebSocketTestPage)
 	portStr := strconv.Itoa(int(*listenPort))
-	log.Println("Listening on :" + portStr)
-	log.Print(http.ListenAndServe(":"+portStr, nil))
+	server := &http.Server{Addr: ":" + portStr}
+	go func() {
+		log.Info().Str("instance", gameConfig.InstanceID).Msg("listening on :" + portStr)
+		err := server.ListenAndServe()
+		if err != http.ErrServerClosed {
+			log.Fatal().Err(err).Msg("http server failed")
+		}
+	}()
+
+	// docker stop присылает SIGTERM и ждёт --time секунд, прежде чем послать SIGKILL.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	log.Info().Str("signal", (<-signals).String()).Msg("shutting down")
+	deadline := time.Now().Add(*drainTime)
+	upgrader.Close()
+	// websocket соединения уже перехвачены и Shutdown их не ждёт, закрывается только listener.
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	_ = server.Shutdown(ctx)
+	roomsManager.Drain(deadline)
+	select {
+	case <-roomsManager.Drained:
+		log.Info().Msg("all rooms completed")
+	case <-time.After(time.Until(deadline) + 5*time.Second):
+		log.Warn().Msg("rooms did not complete in time")
+	}
 }
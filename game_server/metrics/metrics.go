@@ -0,0 +1,48 @@
+// Метрики игрового сервера в формате Prometheus, отдаются по /metrics.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	ActiveRooms = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "game_active_rooms",
+		Help: "Rooms currently served by this instance.",
+	})
+	WaitingPlayers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "game_waiting_players",
+		Help: "Players waiting for a rival on this instance.",
+	})
+	Reconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "game_reconnects_total",
+		Help: "Players who came back to their room with a new connection.",
+	})
+	Redirects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "game_redirects_total",
+		Help: "Players redirected to another instance.",
+	})
+	Messages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_messages_total",
+		Help: "Messages from players by method, unknown methods are counted as 'unknown'.",
+	}, []string{"method"})
+	Errors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_errors_total",
+		Help: "'error_message' sent to players by method and error code.",
+	}, []string{"method", "code"})
+	GameDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "game_duration_seconds",
+		Help:    "Time from room creation to the end of the game.",
+		Buckets: []float64{30, 60, 120, 300, 600, 900, 1200, 1800, 3600},
+	})
+	DroppedMessages = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "game_dropped_messages_total",
+		Help: "Messages to players dropped because the outgoing queue was full, the connection is closed then.",
+	})
+	RoomGoroutines = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "game_room_goroutines",
+		Help: "Goroutines serving rooms: GameMaster and websocket readers/writers, 5 per room.",
+	})
+)
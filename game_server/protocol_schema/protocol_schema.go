@@ -0,0 +1,29 @@
+package protocol_schema
+
+import (
+	"github.com/rs/zerolog/log"
+	"net/http"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
+)
+
+// Схема не меняется за время работы сервера, строится один раз при старте.
+var document []byte
+
+func init() {
+	var err error
+	document, err = types.AsyncAPI()
+	if err != nil {
+		log.Fatal().Err(err).Msg("can not build protocol schema")
+	}
+}
+
+// ProtocolSchema отдаёт AsyncAPI описание протокола внутри WebSocket,
+// по которому фронтенд и боты могут проверять сообщения.
+func ProtocolSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(document)
+	_ = r.Body.Close()
+	return
+}
@@ -0,0 +1,118 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// Кодирование сообщений для конкретного соединения. Игровая логика всегда работает
+// с JSON из этого пакета, а читающие/пишущие в WebSocket горутины комнаты перекодируют
+// сообщения в формат, выбранный клиентом через заголовок Sec-WebSocket-Protocol.
+type Codec interface {
+	// из JSON в формат соединения.
+	Encode(message []byte) (data []byte, err error)
+	// из формата соединения в JSON.
+	Decode(data []byte) (message []byte, err error)
+	// true - бинарные фреймы WebSocket, false - текстовые.
+	Binary() bool
+}
+
+// Поддерживаемые значения Sec-WebSocket-Protocol, в порядке предпочтения сервера.
+const (
+	SubprotocolMessagePack = "rpsarena.msgpack"
+	SubprotocolJSON        = "rpsarena.json"
+)
+
+var Subprotocols = []string{SubprotocolMessagePack, SubprotocolJSON}
+
+// Codec по выбранному при upgrade подпротоколу. Если клиент ничего не запросил - JSON.
+func CodecBySubprotocol(subprotocol string) (codec Codec) {
+	if subprotocol == SubprotocolMessagePack {
+		codec = MessagePackCodec{}
+	} else {
+		codec = JSONCodec{}
+	}
+	return
+}
+
+// JSON как есть, текстовые фреймы. Формат по умолчанию.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(message []byte) ([]byte, error) { return message, nil }
+func (JSONCodec) Decode(data []byte) ([]byte, error)    { return data, nil }
+func (JSONCodec) Binary() bool                          { return false }
+
+// MessagePack с той же структурой сообщений, что и JSON, бинарные фреймы.
+// Компактнее для мобильных клиентов, особенно "download_map".
+type MessagePackCodec struct{}
+
+func (MessagePackCodec) Encode(message []byte) (data []byte, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+	var value interface{}
+	if err = decoder.Decode(&value); err != nil {
+		err = errors.Wrap(err, "in MessagePackCodec.Encode: ")
+		return
+	}
+	data, err = msgpack.Marshal(integerNumbers(value))
+	return
+}
+
+func (MessagePackCodec) Decode(data []byte) (message []byte, err error) {
+	var value interface{}
+	if err = msgpack.Unmarshal(data, &value); err != nil {
+		err = errors.Wrap(err, "in MessagePackCodec.Decode: ")
+		return
+	}
+	message, err = json.Marshal(stringKeys(value))
+	return
+}
+
+func (MessagePackCodec) Binary() bool { return true }
+
+// в протоколе только целые числа (координаты, секунды), передаём их как целые, а не float64.
+func integerNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = integerNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = integerNumbers(item)
+		}
+	}
+	return value
+}
+
+// msgpack допускает ключи любого типа, encoding/json - только строки.
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if keyString, ok := key.(string); ok {
+				result[keyString] = stringKeys(item)
+			}
+		}
+		return result
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = stringKeys(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stringKeys(item)
+		}
+	}
+	return value
+}
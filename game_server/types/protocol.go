@@ -0,0 +1,73 @@
+package types
+
+import (
+	"reflect"
+)
+
+// Версия протокола внутри WebSocket. Увеличивается при любом несовместимом
+// изменении методов или их параметров, клиент узнаёт её через "hello".
+const ProtocolVersion = 2
+
+// Описание одного метода протокола: имя, тип параметра и назначение.
+// Из этих описаний строится ответ на "hello" и машиночитаемая схема протокола.
+type MethodDescription struct {
+	Method      string
+	Parameter   reflect.Type // nil, если параметр не используется и передаётся null.
+	Description string
+}
+
+// Методы, которые клиент вызывает на сервере.
+var ClientMethods = []MethodDescription{
+	{"hello", reflect.TypeOf(Hello{}),
+		"Необязательное первое сообщение: версия протокола клиента."},
+	{"upload_map", reflect.TypeOf(UploadMap{}),
+		"Первоначальная загрузка карты: 14 оружий, ровно один флаг."},
+	{"upload_random_map", nil,
+		"Случайная расстановка персонажей сервером вместо \"upload_map\"."},
+	{"attempt_go_to_cell", reflect.TypeOf(AttemptGoToCell{}),
+		"Движение персонажа в соседнюю клетку или нападение."},
+	{"reassign_weapons", reflect.TypeOf(ReassignWeapons{}),
+		"Перевыбор оружия в ответ на \"weapon_change_request\"."},
+}
+
+// Методы, которые сервер вызывает на клиенте.
+var ServerMethods = []MethodDescription{
+	{"hello", reflect.TypeOf(HelloAnswer{}),
+		"Ответ на \"hello\": версия протокола сервера и доступные методы."},
+	{"setup_countdown", reflect.TypeOf(SetupCountdown{}),
+		"Время на расстановку персонажей, приходит после создания комнаты."},
+	{"download_map", reflect.TypeOf(DownloadMap{}),
+		"Загрузка всей карты."},
+	{"your_rival", reflect.TypeOf(YourRival("")),
+		"Логин соперника."},
+	{"your_turn", reflect.TypeOf(YourTurn(false)),
+		"Чей сейчас ход."},
+	{"move_character", reflect.TypeOf(MoveCharacter{}),
+		"Простое движение персонажа любого игрока."},
+	{"attack", reflect.TypeOf(Attack{}),
+		"Бой, уничтожение одного персонажа."},
+	{"add_weapon", reflect.TypeOf(AddWeapon{}),
+		"Обновление или появление оружия у персонажа."},
+	{"weapon_change_request", reflect.TypeOf(WeaponChangeRequest{}),
+		"Запрос на перевыбор оружия для персонажа."},
+	{"gameover", reflect.TypeOf(GameOver{}),
+		"Конец игры взятием флага."},
+	{"technical_gameover", reflect.TypeOf(TechnicalGameOver{}),
+		"Конец игры без взятия флага: техническое поражение или ничья."},
+	{"server_shutdown", reflect.TypeOf(ServerShutdown{}),
+		"Сервер останавливается, игру надо закончить за указанное время."},
+	{"redirect", reflect.TypeOf(Redirect{}),
+		"Переподключиться к другому экземпляру сервера, соединение закрывается."},
+	{"ack", reflect.TypeOf(Ack{}),
+		"Подтверждение успешной обработки запроса с request_id."},
+	{"error_message", reflect.TypeOf(ErrorMessage{}),
+		"Сообщение об ошибке, следом приходит \"download_map\", если карта загружена."},
+}
+
+// имена методов, принимаемых сервером, для ответа на "hello".
+func ClientMethodNames() (names []string) {
+	for _, method := range ClientMethods {
+		names = append(names, method.Method)
+	}
+	return
+}
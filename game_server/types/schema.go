@@ -0,0 +1,128 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Машиночитаемое описание протокола в формате AsyncAPI 2.0 с JSON Schema параметров.
+// Строится рефлексией по ClientMethods и ServerMethods, поэтому не расходится с типами.
+// Сообщения клиента описаны как publish, сообщения сервера как subscribe
+// канала "/game/v1/entrypoint".
+func AsyncAPI() (document []byte, err error) {
+	messages := map[string]interface{}{}
+	references := func(methods []MethodDescription, prefix string) (oneOf []interface{}) {
+		for _, method := range methods {
+			name := prefix + method.Method
+			messages[name] = map[string]interface{}{
+				"name":    method.Method,
+				"summary": method.Description,
+				"payload": eventSchema(method),
+			}
+			oneOf = append(oneOf, map[string]interface{}{"$ref": "#/components/messages/" + name})
+		}
+		return
+	}
+	asyncAPI := map[string]interface{}{
+		"asyncapi": "2.0.0",
+		"info": map[string]interface{}{
+			"title":   "rpsarena.ru game protocol",
+			"version": strconv.Itoa(ProtocolVersion),
+		},
+		"channels": map[string]interface{}{
+			"/game/v1/entrypoint": map[string]interface{}{
+				"publish": map[string]interface{}{
+					"message": map[string]interface{}{"oneOf": references(ClientMethods, "client.")},
+				},
+				"subscribe": map[string]interface{}{
+					"message": map[string]interface{}{"oneOf": references(ServerMethods, "server.")},
+				},
+			},
+		},
+		"components": map[string]interface{}{
+			"messages": messages,
+		},
+	}
+	document, err = json.MarshalIndent(asyncAPI, "", "  ")
+	return
+}
+
+// схема Event с конкретным методом и параметром.
+func eventSchema(method MethodDescription) (schema map[string]interface{}) {
+	parameter := map[string]interface{}{"type": "null"}
+	if method.Parameter != nil {
+		parameter = JSONSchema(method.Parameter)
+	}
+	schema = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"method":     map[string]interface{}{"type": "string", "const": method.Method},
+			"parameter":  parameter,
+			"request_id": map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"method", "parameter"},
+	}
+	return
+}
+
+// JSON Schema для типа из этого пакета. Понимает теги `json:"name,required"`
+// так же, как easyjson: поля с required обязательны, указатели могут быть null.
+func JSONSchema(t reflect.Type) (schema map[string]interface{}) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema = map[string]interface{}{
+			"oneOf": []interface{}{JSONSchema(t.Elem()), map[string]interface{}{"type": "null"}},
+		}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("json"), ",")
+			name := tag[0]
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = JSONSchema(field.Type)
+			for _, option := range tag[1:] {
+				if option == "required" {
+					required = append(required, name)
+				}
+			}
+		}
+		schema = map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	case reflect.Array:
+		schema = map[string]interface{}{
+			"type":     "array",
+			"items":    JSONSchema(t.Elem()),
+			"minItems": t.Len(),
+			"maxItems": t.Len(),
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 { // easyjson.RawMessage - произвольный json.
+			schema = map[string]interface{}{}
+			break
+		}
+		schema = map[string]interface{}{
+			"type":  "array",
+			"items": JSONSchema(t.Elem()),
+		}
+	case reflect.String:
+		schema = map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		schema = map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema = map[string]interface{}{"type": "integer"}
+	default:
+		schema = map[string]interface{}{}
+	}
+	return
+}
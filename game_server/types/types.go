@@ -18,7 +18,24 @@ type Event struct {
 	// Параметры. Так как неизвестен формат, всё парсится в 2 этапа.
 	// сначала только эта структура, потом по названию выбирается функция, в
 	// надстройку к ней передаётся RawMessage, который парсится в конкретную структуру.
-	Parameter easyjson.RawMessage `json:"parameter,required"`
+	// Не помечен required: easyjson считает null отсутствующим полем, а у "upload_random_map" параметр null.
+	Parameter easyjson.RawMessage `json:"parameter"`
+	// Необязательный идентификатор запроса, выбранный клиентом. Сервер повторяет его
+	// в ответных "ack" и "error_message", что бы клиент мог сопоставить их с запросом.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// первое сообщение клиента, необязательное: версия протокола, на которой он говорит.
+//easyjson:json
+type Hello struct {
+	ProtocolVersion int `json:"protocol_version,required"`
+}
+
+// ответ сервера на "hello": версия протокола сервера и методы, которые он принимает.
+//easyjson:json
+type HelloAnswer struct {
+	ProtocolVersion int      `json:"protocol_version,required"`
+	Methods         []string `json:"methods,required"`
 }
 
 //easyjson:json
@@ -33,8 +50,10 @@ type AttemptGoToCell struct {
 }
 
 func (a *AttemptGoToCell) Check() (err error) {
-	if a.From < 0 && 41 < a.From && a.To < 0 && 41 < a.To {
+	// до проверки соседства: клетки вне поля дальше используются как индексы карты.
+	if a.From < 0 || 41 < a.From || a.To < 0 || 41 < a.To {
 		err = errors.New(strconv.Itoa(a.From) + " or " + strconv.Itoa(a.To) + " out of range.")
+		return
 	}
 	switch a.From - a.To {
 	case -7: // ⍗
@@ -108,10 +127,78 @@ type GameOver struct {
 	To     int  `json:"to,required"`
 }
 
-type ErrorMessage string
+// отправляется обоим игрокам при создании комнаты: сколько секунд осталось на "upload_map".
+//easyjson:json
+type SetupCountdown struct {
+	Seconds int `json:"seconds,required"`
+	// что произойдёт с не успевшим игроком: "generate" - персонажи будут расставлены
+	// случайно, "forfeit" - техническое поражение.
+	OnTimeout string `json:"on_timeout,required"`
+}
+
+// конец игры без взятия флага: техническое поражение или ничья.
+//easyjson:json
+type TechnicalGameOver struct {
+	Winner *bool  `json:"winner,required"` // true - вы, false - ваш соперник, null - ничья
+	Reason string `json:"reason,required"`
+}
+
+// сервер останавливается: за Seconds секунд игру надо закончить, иначе она будет
+// сохранена и прервана. Новые подключения сервер уже не принимает.
+//easyjson:json
+type ServerShutdown struct {
+	Seconds int `json:"seconds,required"`
+}
+
+// игра игрока или ждущий соперник находятся на другом экземпляре сервера: клиент должен
+// переподключиться по адресу URL с той же cookie "SessionId". Сервер сразу закрывает соединение.
+//easyjson:json
+type Redirect struct {
+	Instance string `json:"instance,required"`
+	URL      string `json:"url,required"`
+}
 
-func (em ErrorMessage) MarshalJSON() ([]byte, error) { // easyjson не захотел работать со string
-	return []byte("\"" + em + "\""), nil
+// подтверждение успешной обработки запроса, отправляется только на запросы с request_id.
+//easyjson:json
+type Ack struct {
+	Method string `json:"method,required"`
+}
+
+//easyjson:json
+type ErrorMessage struct {
+	// машиночитаемый код ошибки, например "not_your_turn".
+	Code string `json:"code,required"`
+	// описание для отладки, не предназначено для показа пользователю.
+	Message string `json:"message,required"`
+	// метод, вызвавший ошибку, пустой, если сообщение не удалось разобрать.
+	Method string `json:"method,required"`
+}
+
+// игрок в ответе admin API. Session - начало sha256 токена, сам токен не отдаётся.
+//easyjson:json
+type AdminPlayer struct {
+	Role      int    `json:"role,required"`
+	Login     string `json:"login,required"`
+	Session   string `json:"session,required"`
+	Connected bool   `json:"connected,required"`
+}
+
+// комната в ответе admin API, время в RFC 3339.
+//easyjson:json
+type AdminRoom struct {
+	Room         uint          `json:"room,required"`
+	Phase        string        `json:"phase,required"` // "setup", "game" или "re_election"
+	CreatedAt    string        `json:"created_at,required"`
+	LastActivity string        `json:"last_activity,required"`
+	Players      []AdminPlayer `json:"players,required"`
+}
+
+// ответ GET /game/v1/admin/rooms.
+//easyjson:json
+type AdminRooms struct {
+	Instance string       `json:"instance,required"`
+	Waiting  *AdminPlayer `json:"waiting,required"` // null, если никто не ждёт соперника
+	Rooms    []AdminRoom  `json:"rooms,required"`
 }
 
 //easyjson:json
@@ -111,7 +111,1017 @@ func (v *ServerResponse) UnmarshalJSON(data []byte) error {
 func (v *ServerResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes1(in *jlexer.Lexer, out *GameOver) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes1(in *jlexer.Lexer, out *AdminRooms) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var InstanceSet bool
+	var WaitingSet bool
+	var RoomsSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "instance":
+			out.Instance = string(in.String())
+			InstanceSet = true
+		case "waiting":
+			if in.IsNull() {
+				in.Skip()
+				out.Waiting = nil
+			} else {
+				if out.Waiting == nil {
+					out.Waiting = new(AdminPlayer)
+				}
+				(*out.Waiting).UnmarshalEasyJSON(in)
+			}
+			WaitingSet = true
+		case "rooms":
+			if in.IsNull() {
+				in.Skip()
+				out.Rooms = nil
+			} else {
+				in.Delim('[')
+				if out.Rooms == nil {
+					if !in.IsDelim(']') {
+						out.Rooms = make([]AdminRoom, 0, 0)
+					} else {
+						out.Rooms = []AdminRoom{}
+					}
+				} else {
+					out.Rooms = (out.Rooms)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 AdminRoom
+					(v1).UnmarshalEasyJSON(in)
+					out.Rooms = append(out.Rooms, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+			RoomsSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !InstanceSet {
+		in.AddError(fmt.Errorf("key 'instance' is required"))
+	}
+	if !WaitingSet {
+		in.AddError(fmt.Errorf("key 'waiting' is required"))
+	}
+	if !RoomsSet {
+		in.AddError(fmt.Errorf("key 'rooms' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes1(out *jwriter.Writer, in AdminRooms) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"instance\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Instance))
+	}
+	{
+		const prefix string = ",\"waiting\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		if in.Waiting == nil {
+			out.RawString("null")
+		} else {
+			(*in.Waiting).MarshalEasyJSON(out)
+		}
+	}
+	{
+		const prefix string = ",\"rooms\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		if in.Rooms == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.Rooms {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				(v3).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v AdminRooms) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v AdminRooms) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *AdminRooms) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *AdminRooms) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes1(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes2(in *jlexer.Lexer, out *AdminRoom) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var RoomSet bool
+	var PhaseSet bool
+	var CreatedAtSet bool
+	var LastActivitySet bool
+	var PlayersSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "room":
+			out.Room = uint(in.Uint())
+			RoomSet = true
+		case "phase":
+			out.Phase = string(in.String())
+			PhaseSet = true
+		case "created_at":
+			out.CreatedAt = string(in.String())
+			CreatedAtSet = true
+		case "last_activity":
+			out.LastActivity = string(in.String())
+			LastActivitySet = true
+		case "players":
+			if in.IsNull() {
+				in.Skip()
+				out.Players = nil
+			} else {
+				in.Delim('[')
+				if out.Players == nil {
+					if !in.IsDelim(']') {
+						out.Players = make([]AdminPlayer, 0, 1)
+					} else {
+						out.Players = []AdminPlayer{}
+					}
+				} else {
+					out.Players = (out.Players)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v4 AdminPlayer
+					(v4).UnmarshalEasyJSON(in)
+					out.Players = append(out.Players, v4)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+			PlayersSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !RoomSet {
+		in.AddError(fmt.Errorf("key 'room' is required"))
+	}
+	if !PhaseSet {
+		in.AddError(fmt.Errorf("key 'phase' is required"))
+	}
+	if !CreatedAtSet {
+		in.AddError(fmt.Errorf("key 'created_at' is required"))
+	}
+	if !LastActivitySet {
+		in.AddError(fmt.Errorf("key 'last_activity' is required"))
+	}
+	if !PlayersSet {
+		in.AddError(fmt.Errorf("key 'players' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes2(out *jwriter.Writer, in AdminRoom) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"room\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Uint(uint(in.Room))
+	}
+	{
+		const prefix string = ",\"phase\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Phase))
+	}
+	{
+		const prefix string = ",\"created_at\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.CreatedAt))
+	}
+	{
+		const prefix string = ",\"last_activity\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.LastActivity))
+	}
+	{
+		const prefix string = ",\"players\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		if in.Players == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v5, v6 := range in.Players {
+				if v5 > 0 {
+					out.RawByte(',')
+				}
+				(v6).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v AdminRoom) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v AdminRoom) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *AdminRoom) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *AdminRoom) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes2(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes3(in *jlexer.Lexer, out *AdminPlayer) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var RoleSet bool
+	var LoginSet bool
+	var SessionSet bool
+	var ConnectedSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "role":
+			out.Role = int(in.Int())
+			RoleSet = true
+		case "login":
+			out.Login = string(in.String())
+			LoginSet = true
+		case "session":
+			out.Session = string(in.String())
+			SessionSet = true
+		case "connected":
+			out.Connected = bool(in.Bool())
+			ConnectedSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !RoleSet {
+		in.AddError(fmt.Errorf("key 'role' is required"))
+	}
+	if !LoginSet {
+		in.AddError(fmt.Errorf("key 'login' is required"))
+	}
+	if !SessionSet {
+		in.AddError(fmt.Errorf("key 'session' is required"))
+	}
+	if !ConnectedSet {
+		in.AddError(fmt.Errorf("key 'connected' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes3(out *jwriter.Writer, in AdminPlayer) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"role\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.Role))
+	}
+	{
+		const prefix string = ",\"login\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Login))
+	}
+	{
+		const prefix string = ",\"session\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Session))
+	}
+	{
+		const prefix string = ",\"connected\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Bool(bool(in.Connected))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v AdminPlayer) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes3(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v AdminPlayer) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes3(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *AdminPlayer) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes3(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *AdminPlayer) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes3(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes4(in *jlexer.Lexer, out *ErrorMessage) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var CodeSet bool
+	var MessageSet bool
+	var MethodSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "code":
+			out.Code = string(in.String())
+			CodeSet = true
+		case "message":
+			out.Message = string(in.String())
+			MessageSet = true
+		case "method":
+			out.Method = string(in.String())
+			MethodSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !CodeSet {
+		in.AddError(fmt.Errorf("key 'code' is required"))
+	}
+	if !MessageSet {
+		in.AddError(fmt.Errorf("key 'message' is required"))
+	}
+	if !MethodSet {
+		in.AddError(fmt.Errorf("key 'method' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes4(out *jwriter.Writer, in ErrorMessage) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"code\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Code))
+	}
+	{
+		const prefix string = ",\"message\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Message))
+	}
+	{
+		const prefix string = ",\"method\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Method))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ErrorMessage) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes4(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ErrorMessage) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes4(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ErrorMessage) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes4(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ErrorMessage) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes4(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes5(in *jlexer.Lexer, out *Ack) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var MethodSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "method":
+			out.Method = string(in.String())
+			MethodSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !MethodSet {
+		in.AddError(fmt.Errorf("key 'method' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes5(out *jwriter.Writer, in Ack) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"method\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Method))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Ack) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes5(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Ack) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes5(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Ack) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes5(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Ack) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes5(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes6(in *jlexer.Lexer, out *Redirect) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var InstanceSet bool
+	var URLSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "instance":
+			out.Instance = string(in.String())
+			InstanceSet = true
+		case "url":
+			out.URL = string(in.String())
+			URLSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !InstanceSet {
+		in.AddError(fmt.Errorf("key 'instance' is required"))
+	}
+	if !URLSet {
+		in.AddError(fmt.Errorf("key 'url' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes6(out *jwriter.Writer, in Redirect) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"instance\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Instance))
+	}
+	{
+		const prefix string = ",\"url\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.URL))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Redirect) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes6(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Redirect) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes6(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Redirect) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes6(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Redirect) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes6(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes7(in *jlexer.Lexer, out *ServerShutdown) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var SecondsSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "seconds":
+			out.Seconds = int(in.Int())
+			SecondsSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !SecondsSet {
+		in.AddError(fmt.Errorf("key 'seconds' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes7(out *jwriter.Writer, in ServerShutdown) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"seconds\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.Seconds))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ServerShutdown) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes7(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ServerShutdown) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes7(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ServerShutdown) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes7(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ServerShutdown) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes7(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes8(in *jlexer.Lexer, out *TechnicalGameOver) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var WinnerSet bool
+	var ReasonSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "winner":
+			if in.IsNull() {
+				in.Skip()
+				out.Winner = nil
+			} else {
+				if out.Winner == nil {
+					out.Winner = new(bool)
+				}
+				*out.Winner = bool(in.Bool())
+			}
+			WinnerSet = true
+		case "reason":
+			out.Reason = string(in.String())
+			ReasonSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !WinnerSet {
+		in.AddError(fmt.Errorf("key 'winner' is required"))
+	}
+	if !ReasonSet {
+		in.AddError(fmt.Errorf("key 'reason' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes8(out *jwriter.Writer, in TechnicalGameOver) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"winner\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		if in.Winner == nil {
+			out.RawString("null")
+		} else {
+			out.Bool(bool(*in.Winner))
+		}
+	}
+	{
+		const prefix string = ",\"reason\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Reason))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v TechnicalGameOver) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes8(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v TechnicalGameOver) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes8(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *TechnicalGameOver) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes8(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *TechnicalGameOver) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes8(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes9(in *jlexer.Lexer, out *SetupCountdown) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var SecondsSet bool
+	var OnTimeoutSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "seconds":
+			out.Seconds = int(in.Int())
+			SecondsSet = true
+		case "on_timeout":
+			out.OnTimeout = string(in.String())
+			OnTimeoutSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !SecondsSet {
+		in.AddError(fmt.Errorf("key 'seconds' is required"))
+	}
+	if !OnTimeoutSet {
+		in.AddError(fmt.Errorf("key 'on_timeout' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes9(out *jwriter.Writer, in SetupCountdown) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"seconds\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.Seconds))
+	}
+	{
+		const prefix string = ",\"on_timeout\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.OnTimeout))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v SetupCountdown) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes9(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v SetupCountdown) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes9(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *SetupCountdown) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes9(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *SetupCountdown) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes9(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes10(in *jlexer.Lexer, out *GameOver) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -161,7 +1171,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes1(in *jlex
 		in.AddError(fmt.Errorf("key 'to' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes1(out *jwriter.Writer, in GameOver) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes10(out *jwriter.Writer, in GameOver) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -201,27 +1211,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes1(out *jwr
 // MarshalJSON supports json.Marshaler interface
 func (v GameOver) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes1(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes10(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v GameOver) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes1(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes10(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *GameOver) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes1(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes10(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *GameOver) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes1(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes10(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes2(in *jlexer.Lexer, out *WeaponChangeRequest) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes11(in *jlexer.Lexer, out *WeaponChangeRequest) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -257,7 +1267,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes2(in *jlex
 		in.AddError(fmt.Errorf("key 'character_position' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes2(out *jwriter.Writer, in WeaponChangeRequest) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes11(out *jwriter.Writer, in WeaponChangeRequest) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -277,27 +1287,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes2(out *jwr
 // MarshalJSON supports json.Marshaler interface
 func (v WeaponChangeRequest) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes2(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes11(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v WeaponChangeRequest) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes2(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes11(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *WeaponChangeRequest) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes2(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes11(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *WeaponChangeRequest) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes2(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes11(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes3(in *jlexer.Lexer, out *AddWeapon) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes12(in *jlexer.Lexer, out *AddWeapon) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -340,7 +1350,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes3(in *jlex
 		in.AddError(fmt.Errorf("key 'weapon' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes3(out *jwriter.Writer, in AddWeapon) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes12(out *jwriter.Writer, in AddWeapon) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -370,27 +1380,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes3(out *jwr
 // MarshalJSON supports json.Marshaler interface
 func (v AddWeapon) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes3(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes12(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v AddWeapon) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes3(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes12(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *AddWeapon) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes3(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes12(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *AddWeapon) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes3(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes12(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes4(in *jlexer.Lexer, out *Attack) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes13(in *jlexer.Lexer, out *Attack) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -433,7 +1443,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes4(in *jlex
 		in.AddError(fmt.Errorf("key 'loser' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes4(out *jwriter.Writer, in Attack) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes13(out *jwriter.Writer, in Attack) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -463,27 +1473,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes4(out *jwr
 // MarshalJSON supports json.Marshaler interface
 func (v Attack) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes4(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes13(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Attack) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes4(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes13(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Attack) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes4(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes13(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Attack) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes4(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes13(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes5(in *jlexer.Lexer, out *AttackingСharacter) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes14(in *jlexer.Lexer, out *AttackingСharacter) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -526,7 +1536,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes5(in *jlex
 		in.AddError(fmt.Errorf("key 'weapon' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes5(out *jwriter.Writer, in AttackingСharacter) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes14(out *jwriter.Writer, in AttackingСharacter) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -556,27 +1566,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes5(out *jwr
 // MarshalJSON supports json.Marshaler interface
 func (v AttackingСharacter) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes5(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes14(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v AttackingСharacter) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes5(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes14(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *AttackingСharacter) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes5(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes14(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *AttackingСharacter) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes5(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes14(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes6(in *jlexer.Lexer, out *MoveCharacter) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes15(in *jlexer.Lexer, out *MoveCharacter) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -619,7 +1629,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes6(in *jlex
 		in.AddError(fmt.Errorf("key 'to' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes6(out *jwriter.Writer, in MoveCharacter) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes15(out *jwriter.Writer, in MoveCharacter) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -649,45 +1659,45 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes6(out *jwr
 // MarshalJSON supports json.Marshaler interface
 func (v MoveCharacter) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes6(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes15(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v MoveCharacter) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes6(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes15(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *MoveCharacter) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes6(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes15(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *MoveCharacter) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes6(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes15(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes7(in *jlexer.Lexer, out *DownloadMap) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes16(in *jlexer.Lexer, out *DownloadMap) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		in.Skip()
 	} else {
 		in.Delim('[')
-		v1 := 0
+		v7 := 0
 		for !in.IsDelim(']') {
-			if v1 < 42 {
+			if v7 < 42 {
 				if in.IsNull() {
 					in.Skip()
-					(*out)[v1] = nil
+					(*out)[v7] = nil
 				} else {
-					if (*out)[v1] == nil {
-						(*out)[v1] = new(MapCell)
+					if (*out)[v7] == nil {
+						(*out)[v7] = new(MapCell)
 					}
-					(*(*out)[v1]).UnmarshalEasyJSON(in)
+					(*(*out)[v7]).UnmarshalEasyJSON(in)
 				}
-				v1++
+				v7++
 			} else {
 				in.SkipRecursive()
 			}
@@ -699,16 +1709,16 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes7(in *jlex
 		in.Consumed()
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes7(out *jwriter.Writer, in DownloadMap) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes16(out *jwriter.Writer, in DownloadMap) {
 	out.RawByte('[')
-	for v2 := range in {
-		if v2 > 0 {
+	for v8 := range in {
+		if v8 > 0 {
 			out.RawByte(',')
 		}
-		if (in)[v2] == nil {
+		if (in)[v8] == nil {
 			out.RawString("null")
 		} else {
-			(*(in)[v2]).MarshalEasyJSON(out)
+			(*(in)[v8]).MarshalEasyJSON(out)
 		}
 	}
 	out.RawByte(']')
@@ -717,27 +1727,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes7(out *jwr
 // MarshalJSON supports json.Marshaler interface
 func (v DownloadMap) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes7(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes16(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v DownloadMap) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes7(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes16(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *DownloadMap) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes7(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes16(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *DownloadMap) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes7(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes16(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes8(in *jlexer.Lexer, out *MapCell) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes17(in *jlexer.Lexer, out *MapCell) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -788,7 +1798,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes8(in *jlex
 		in.AddError(fmt.Errorf("key 'weapon' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes8(out *jwriter.Writer, in MapCell) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes17(out *jwriter.Writer, in MapCell) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -822,27 +1832,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes8(out *jwr
 // MarshalJSON supports json.Marshaler interface
 func (v MapCell) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes8(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes17(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v MapCell) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes8(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes17(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *MapCell) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes8(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes17(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *MapCell) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes8(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes17(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes9(in *jlexer.Lexer, out *ReassignWeapons) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes18(in *jlexer.Lexer, out *ReassignWeapons) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -885,7 +1895,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes9(in *jlex
 		in.AddError(fmt.Errorf("key 'character_position' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes9(out *jwriter.Writer, in ReassignWeapons) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes18(out *jwriter.Writer, in ReassignWeapons) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -915,27 +1925,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes9(out *jwr
 // MarshalJSON supports json.Marshaler interface
 func (v ReassignWeapons) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes9(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes18(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v ReassignWeapons) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes9(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes18(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *ReassignWeapons) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes9(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes18(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *ReassignWeapons) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes9(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes18(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes10(in *jlexer.Lexer, out *AttemptGoToCell) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes19(in *jlexer.Lexer, out *AttemptGoToCell) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -978,7 +1988,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes10(in *jle
 		in.AddError(fmt.Errorf("key 'to' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes10(out *jwriter.Writer, in AttemptGoToCell) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes19(out *jwriter.Writer, in AttemptGoToCell) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -1008,27 +2018,27 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes10(out *jw
 // MarshalJSON supports json.Marshaler interface
 func (v AttemptGoToCell) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes10(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes19(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v AttemptGoToCell) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes10(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes19(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *AttemptGoToCell) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes10(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes19(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *AttemptGoToCell) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes10(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes19(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes11(in *jlexer.Lexer, out *UploadMap) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes20(in *jlexer.Lexer, out *UploadMap) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -1053,11 +2063,11 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes11(in *jle
 				in.Skip()
 			} else {
 				in.Delim('[')
-				v3 := 0
+				v9 := 0
 				for !in.IsDelim(']') {
-					if v3 < 14 {
-						(out.Weapons)[v3] = string(in.String())
-						v3++
+					if v9 < 14 {
+						(out.Weapons)[v9] = string(in.String())
+						v9++
 					} else {
 						in.SkipRecursive()
 					}
@@ -1079,7 +2089,7 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes11(in *jle
 		in.AddError(fmt.Errorf("key 'weapons' is required"))
 	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes11(out *jwriter.Writer, in UploadMap) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes20(out *jwriter.Writer, in UploadMap) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -1092,11 +2102,11 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes11(out *jw
 			out.RawString(prefix)
 		}
 		out.RawByte('[')
-		for v4 := range in.Weapons {
-			if v4 > 0 {
+		for v10 := range in.Weapons {
+			if v10 > 0 {
 				out.RawByte(',')
 			}
-			out.String(string((in.Weapons)[v4]))
+			out.String(string((in.Weapons)[v10]))
 		}
 		out.RawByte(']')
 	}
@@ -1106,27 +2116,228 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes11(out *jw
 // MarshalJSON supports json.Marshaler interface
 func (v UploadMap) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes11(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes20(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v UploadMap) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes11(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes20(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *UploadMap) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes11(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes20(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *UploadMap) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes11(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes20(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes21(in *jlexer.Lexer, out *HelloAnswer) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var ProtocolVersionSet bool
+	var MethodsSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "protocol_version":
+			out.ProtocolVersion = int(in.Int())
+			ProtocolVersionSet = true
+		case "methods":
+			if in.IsNull() {
+				in.Skip()
+				out.Methods = nil
+			} else {
+				in.Delim('[')
+				if out.Methods == nil {
+					if !in.IsDelim(']') {
+						out.Methods = make([]string, 0, 4)
+					} else {
+						out.Methods = []string{}
+					}
+				} else {
+					out.Methods = (out.Methods)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v11 string
+					v11 = string(in.String())
+					out.Methods = append(out.Methods, v11)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+			MethodsSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !ProtocolVersionSet {
+		in.AddError(fmt.Errorf("key 'protocol_version' is required"))
+	}
+	if !MethodsSet {
+		in.AddError(fmt.Errorf("key 'methods' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes21(out *jwriter.Writer, in HelloAnswer) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"protocol_version\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.ProtocolVersion))
+	}
+	{
+		const prefix string = ",\"methods\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		if in.Methods == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v12, v13 := range in.Methods {
+				if v12 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v13))
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v HelloAnswer) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes21(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v HelloAnswer) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes21(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *HelloAnswer) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes21(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *HelloAnswer) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes21(l, v)
+}
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes22(in *jlexer.Lexer, out *Hello) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	var ProtocolVersionSet bool
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "protocol_version":
+			out.ProtocolVersion = int(in.Int())
+			ProtocolVersionSet = true
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+	if !ProtocolVersionSet {
+		in.AddError(fmt.Errorf("key 'protocol_version' is required"))
+	}
+}
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes22(out *jwriter.Writer, in Hello) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"protocol_version\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.ProtocolVersion))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Hello) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes22(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Hello) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes22(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Hello) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes22(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Hello) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes22(l, v)
 }
-func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes12(in *jlexer.Lexer, out *Event) {
+func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes23(in *jlexer.Lexer, out *Event) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -1136,7 +2347,6 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes12(in *jle
 		return
 	}
 	var MethodSet bool
-	var ParameterSet bool
 	in.Delim('{')
 	for !in.IsDelim('}') {
 		key := in.UnsafeString()
@@ -1152,7 +2362,8 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes12(in *jle
 			MethodSet = true
 		case "parameter":
 			(out.Parameter).UnmarshalEasyJSON(in)
-			ParameterSet = true
+		case "request_id":
+			out.RequestID = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -1165,11 +2376,8 @@ func easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes12(in *jle
 	if !MethodSet {
 		in.AddError(fmt.Errorf("key 'method' is required"))
 	}
-	if !ParameterSet {
-		in.AddError(fmt.Errorf("key 'parameter' is required"))
-	}
 }
-func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes12(out *jwriter.Writer, in Event) {
+func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes23(out *jwriter.Writer, in Event) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -1193,29 +2401,39 @@ func easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes12(out *jw
 		}
 		(in.Parameter).MarshalEasyJSON(out)
 	}
+	if in.RequestID != "" {
+		const prefix string = ",\"request_id\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.RequestID))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v Event) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes12(&w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes23(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Event) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes12(w, v)
+	easyjson6601e8cdEncodeGithubComGoParkMailRu2018242GameServerTypes23(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Event) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes12(&r, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes23(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Event) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes12(l, v)
+	easyjson6601e8cdDecodeGithubComGoParkMailRu2018242GameServerTypes23(l, v)
 }
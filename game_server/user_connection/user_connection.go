@@ -1,13 +1,42 @@
 package user_connection
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+
 	"github.com/gorilla/websocket"
+
+	"github.com/OlegSchwann/rpsarena-ru-backend/game_server/types"
 )
 
 // Соединение пользователя, заведомо валидное, за производство отвечает connection_upgrader.
 type UserConnection struct {
-	Login      string
-	Avatar     string
-	Token      string
+	Login  string
+	Avatar string
+	Token  string
+	// sha256 токена в hex. Токен даёт доступ к аккаунту, поэтому в реестр и
+	// снимки комнат попадает только хеш.
+	TokenHash  string
 	Connection *websocket.Conn
+	// формат сообщений, выбранный клиентом при upgrade.
+	Codec types.Codec
+	// 1 - чтение из соединения завершилось ошибкой, меняется только через atomic.
+	lost int32
+}
+
+// отмечает соединение разорванным, вызывается читающей горутиной комнаты.
+func (uc *UserConnection) MarkLost() {
+	atomic.StoreInt32(&uc.lost, 1)
+	return
+}
+
+// соединение установлено и ещё не разорвано. Безопасно вызывать из любой горутины.
+func (uc *UserConnection) Connected() bool {
+	return uc.Connection != nil && atomic.LoadInt32(&uc.lost) == 0
+}
+
+func TokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
 }
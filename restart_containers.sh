@@ -4,12 +4,15 @@
   --name 'authorization' \
   --network 'rpsarena-net' \
   --volume "/var/www/media/images":"/var/www/media/images" \
+  --env INTERNAL_TOKEN \
   --detach \
   --rm olegschwann/authorization_server:latest &&
   sudo docker pull olegschwann/game_server:latest && 
-  sudo docker kill game
+  sudo docker stop --time 70 game
   sudo docker run \
   --name 'game' \
   --network 'rpsarena-net' \
+  --volume "/var/lib/game_server/rooms":"/var/lib/game_server/rooms" \
+  --env INTERNAL_TOKEN \
   --detach \
   --rm olegschwann/game_server:latest 